@@ -1,29 +1,68 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+
 	"github.com/zan8in/gologger"
 	"github.com/zan8in/pyxis/pkg/pyxis"
+	"github.com/zan8in/pyxis/pkg/server"
 )
 
 func main() {
-	// 设置信号处理
+	// pyxis serve 以 HTTP API 服务方式运行
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		serve()
+		return
+	}
+
+	options := pyxis.ParseOptions()
+
+	runner, err := pyxis.NewRunner(options)
+	if err != nil {
+		gologger.Fatal().Msg(err.Error())
+	}
+
+	// 设置信号处理：第一次中断时停止扫描并保存结果，第二次直接退出
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
-		gologger.Info().Msg("收到退出信号，正在停止...")
+		gologger.Info().Msg("收到退出信号，正在保存结果并停止，再次中断将直接退出...")
+		runner.Stop()
+		<-c
 		os.Exit(1)
 	}()
 
-	options := pyxis.ParseOptions()
+	runner.Run()
+}
 
-	runner, err := pyxis.NewRunner(options)
-	if err != nil {
+func serve() {
+	options := server.ParseOptions()
+
+	s := server.New(options)
+
+	// ListenAndServe 在 Shutdown 开始时就返回，等待 Shutdown 完成后再退出
+	shutdown := make(chan struct{})
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer close(shutdown)
+		<-c
+		gologger.Info().Msg("收到退出信号，正在取消任务并关闭服务...")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			gologger.Error().Msg(err.Error())
+		}
+	}()
+
+	if err := s.ListenAndServe(); err != nil {
 		gologger.Fatal().Msg(err.Error())
 	}
-
-	runner.Run()
+	<-shutdown
 }
@@ -1,19 +1,21 @@
 package favicon
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/zan8in/pyxis/pkg/http/retryhttpclient"
+	"github.com/zan8in/pyxis/pkg/result"
 	"github.com/zan8in/pyxis/pkg/util/faviconhashutil"
 	"github.com/zan8in/pyxis/pkg/util/stringutil"
 )
 
 func HandleFaviconHash(target, body string) (string, error) {
 	if strings.HasSuffix(target, ".ico") {
-		return doFaviconHash(target), nil
+		return doFaviconHash(context.Background(), target), nil
 	}
 
 	potentialURLs, err := extractPotentialFavIconsURLs(body)
@@ -68,7 +70,27 @@ func extractPotentialFavIconsURLs(body string) ([]string, error) {
 	return potentialURLs, nil
 }
 
+// Fetcher 使用指定的 HTTP 客户端请求 favicon，client 为 nil 时使用 retryhttpclient 的默认客户端
+type Fetcher struct {
+	client *retryhttpclient.Client
+}
+
+var defaultFetcher = &Fetcher{}
+
+func NewFetcher(client *retryhttpclient.Client) *Fetcher {
+	return &Fetcher{client: client}
+}
+
 func FaviconHash(target, body string) string {
+	return defaultFetcher.FaviconHash(context.Background(), target, body)
+}
+
+// FaviconHashContext 与 FaviconHash 相同，ctx 取消时中止 favicon 请求
+func FaviconHashContext(ctx context.Context, target, body string) string {
+	return defaultFetcher.FaviconHash(ctx, target, body)
+}
+
+func (f *Fetcher) FaviconHash(ctx context.Context, target, body string) string {
 	// HandleFaviconHash(target, body)
 	if target == "" {
 		return ""
@@ -76,14 +98,21 @@ func FaviconHash(target, body string) string {
 	if body == "" {
 		return ""
 	}
+	if strings.HasSuffix(target, ".ico") {
+		return f.hash(ctx, target)
+	}
 	if url, err := HandleFaviconHash(target, body); err == nil && len(url) > 0 {
-		return doFaviconHash(url)
+		return f.hash(ctx, url)
 	}
 	return ""
 }
 
-func doFaviconHash(url string) string {
-	result, err := retryhttpclient.Get(url)
+func doFaviconHash(ctx context.Context, url string) string {
+	return defaultFetcher.hash(ctx, url)
+}
+
+func (f *Fetcher) hash(ctx context.Context, url string) string {
+	result, err := f.get(ctx, url)
 	if err != nil {
 		return ""
 	}
@@ -95,3 +124,10 @@ func doFaviconHash(url string) string {
 	}
 	return ""
 }
+
+func (f *Fetcher) get(ctx context.Context, url string) (result.HostResult, error) {
+	if f.client == nil {
+		return retryhttpclient.GetContext(ctx, url)
+	}
+	return f.client.GetContext(ctx, url)
+}
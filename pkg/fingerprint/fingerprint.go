@@ -0,0 +1,192 @@
+package fingerprint
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zan8in/pyxis/pkg/result"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ConditionAnd = "and"
+	ConditionOr  = "or"
+)
+
+// Rule 是一条自定义指纹规则，Matchers 按 Condition 组合，默认 or。
+// Version 是可选的版本提取器，使用 regex 的第一个分组作为版本号
+type Rule struct {
+	ID        string     `yaml:"id" json:"id"`
+	Name      string     `yaml:"name" json:"name"`
+	Vendor    string     `yaml:"vendor" json:"vendor"`
+	Category  string     `yaml:"category" json:"category"`
+	Condition string     `yaml:"condition" json:"condition"`
+	Matchers  []*Matcher `yaml:"matchers" json:"matchers"`
+	Version   *Matcher   `yaml:"version" json:"version"`
+}
+
+// Load 加载自定义指纹规则，path 可以是单个文件或目录（递归加载 .yaml/.yml/.json 文件）。
+// 每个文件包含一条规则或规则列表，JSON 作为 YAML 的子集使用同一个解析器
+func Load(path string) ([]*Rule, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return loadFile(path)
+	}
+
+	var rules []*Rule
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		fileRules, err := loadFile(file)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, fileRules...)
+		return nil
+	})
+
+	return rules, err
+}
+
+func loadFile(file string) ([]*Rule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*Rule
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '-') {
+		err = yaml.Unmarshal(data, &rules)
+	} else {
+		rule := &Rule{}
+		if err = yaml.Unmarshal(data, rule); err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	for i, rule := range rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", file, i+1, err)
+		}
+		// 未指定 ID 时使用 文件名#序号，便于定位命中的规则
+		if len(rule.ID) == 0 {
+			rule.ID = fmt.Sprintf("%s#%d", filepath.Base(file), i+1)
+		}
+	}
+
+	return rules, nil
+}
+
+func (rule *Rule) compile() error {
+	if rule == nil || len(strings.TrimSpace(rule.Name)) == 0 {
+		return fmt.Errorf("name is required")
+	}
+	if len(rule.Matchers) == 0 {
+		return fmt.Errorf("%s: no matchers", rule.Name)
+	}
+
+	var err error
+	if rule.Condition, err = condition(rule.Condition); err != nil {
+		return fmt.Errorf("%s: %w", rule.Name, err)
+	}
+
+	for i, matcher := range rule.Matchers {
+		// YAML/JSON 中的 null 项解析为 nil
+		if matcher == nil {
+			return fmt.Errorf("%s: matcher %d is empty", rule.Name, i+1)
+		}
+		if err := matcher.compile(); err != nil {
+			return fmt.Errorf("%s: %w", rule.Name, err)
+		}
+	}
+
+	if rule.Version != nil {
+		if err := rule.Version.compileVersion(); err != nil {
+			return fmt.Errorf("%s: version: %w", rule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func condition(c string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(c)) {
+	case "", ConditionOr:
+		return ConditionOr, nil
+	case ConditionAnd:
+		return ConditionAnd, nil
+	}
+	return "", fmt.Errorf("unsupported condition %q", c)
+}
+
+// Match 判断结果是否命中规则，命中时返回包含证据和版本的指纹
+func (rule *Rule) Match(rst *result.HostResult) (result.Fingerprint, bool) {
+	var evidence []string
+
+	matched := rule.Condition == ConditionAnd
+	for _, matcher := range rule.Matchers {
+		ok, e := matcher.Match(rst)
+		if ok {
+			evidence = append(evidence, e)
+		}
+		if ok && rule.Condition == ConditionOr {
+			matched = true
+			break
+		}
+		if !ok && rule.Condition == ConditionAnd {
+			matched = false
+			break
+		}
+	}
+	if !matched {
+		return result.Fingerprint{}, false
+	}
+
+	fp := result.Fingerprint{
+		Name:     rule.Name,
+		Vendor:   rule.Vendor,
+		Category: rule.Category,
+		Evidence: strings.Join(evidence, "; "),
+		RuleID:   rule.ID,
+	}
+	if rule.Version != nil {
+		fp.Version = rule.Version.Extract(rst)
+	}
+	return fp, true
+}
+
+// Match 返回结果命中的全部规则，同名规则只保留先加载的一条
+func Match(rules []*Rule, rst *result.HostResult) []result.Fingerprint {
+	var (
+		fps  []result.Fingerprint
+		seen = make(map[string]struct{})
+	)
+	for _, rule := range rules {
+		if _, ok := seen[rule.Name]; ok {
+			continue
+		}
+		if fp, ok := rule.Match(rst); ok {
+			seen[rule.Name] = struct{}{}
+			fps = append(fps, fp)
+		}
+	}
+	return fps
+}
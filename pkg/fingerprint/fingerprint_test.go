@@ -0,0 +1,236 @@
+package fingerprint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zan8in/pyxis/pkg/result"
+)
+
+func testResult() *result.HostResult {
+	return &result.HostResult{
+		StatusCode:  200,
+		Title:       "Dashboard - Grafana",
+		Body:        `<html><head><title>Dashboard - Grafana</title></head><body><div id="app">Welcome to Grafana v10.2.3 (build abc)</div></body></html>`,
+		RawHeader:   []byte("HTTP/1.1 200 OK\r\nServer: nginx/1.25.3\r\nX-Powered-By: Express\r\n"),
+		Headers:     map[string]string{"server": "nginx/1.25.3", "x-powered-by": "Express"},
+		FaviconHash: "-1234567",
+		TLSInfo: &result.TLSInfo{
+			SubjectCN: "grafana.example.com",
+			Issuer:    "R3",
+			SANs:      []string{"grafana.example.com", "metrics.example.com"},
+		},
+	}
+}
+
+func compiled(t *testing.T, rule *Rule) *Rule {
+	t.Helper()
+	if err := rule.compile(); err != nil {
+		t.Fatalf("compile %s: %v", rule.Name, err)
+	}
+	return rule
+}
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         *Rule
+		want         bool
+		wantVersion  string
+		wantEvidence string
+	}{
+		{
+			name: "or any matcher",
+			rule: &Rule{Name: "a", Matchers: []*Matcher{
+				{Type: TypeTitle, Words: []string{"kibana"}},
+				{Type: TypeStatus, Status: []int{200}},
+			}},
+			want:         true,
+			wantEvidence: "status: 200",
+		},
+		{
+			name: "or no matcher",
+			rule: &Rule{Name: "a", Matchers: []*Matcher{
+				{Type: TypeTitle, Words: []string{"kibana"}},
+				{Type: TypeStatus, Status: []int{404}},
+			}},
+			want: false,
+		},
+		{
+			name: "and all matchers",
+			rule: &Rule{Name: "a", Condition: "AND", Matchers: []*Matcher{
+				{Type: TypeTitle, Words: []string{"GRAFANA"}},
+				{Type: TypeHeader, Key: "Server", Regex: []string{`nginx/[\d.]+`}},
+				{Type: TypeCert, Field: "san", Words: []string{"metrics.example.com"}},
+			}},
+			want:         true,
+			wantEvidence: "title: Dashboard - Grafana; header.server: nginx/1.25.3; cert.san: grafana.example.com metrics.example.com",
+		},
+		{
+			name: "and one matcher missing",
+			rule: &Rule{Name: "a", Condition: "and", Matchers: []*Matcher{
+				{Type: TypeTitle, Words: []string{"grafana"}},
+				{Type: TypeFavicon, Hash: []string{"42"}},
+			}},
+			want: false,
+		},
+		{
+			name: "and inside a matcher",
+			rule: &Rule{Name: "a", Matchers: []*Matcher{
+				{Type: TypeBody, Condition: "and", Words: []string{"grafana", "kibana"}},
+			}},
+			want: false,
+		},
+		{
+			name: "header without key matches raw header",
+			rule: &Rule{Name: "a", Matchers: []*Matcher{
+				{Type: TypeHeader, Words: []string{"x-powered-by: express"}},
+			}},
+			want:         true,
+			wantEvidence: "header: rver: nginx/1.25.3 X-Powered-By: Express",
+		},
+		{
+			name: "missing header",
+			rule: &Rule{Name: "a", Matchers: []*Matcher{
+				{Type: TypeHeader, Key: "x-grafana", Words: []string{"1"}},
+			}},
+			want: false,
+		},
+		{
+			name: "body evidence snippet",
+			rule: &Rule{Name: "a", Matchers: []*Matcher{
+				{Type: TypeBody, Regex: []string{`v\d+\.\d+\.\d+`}},
+			}},
+			want:         true,
+			wantEvidence: "body: >Welcome to Grafana v10.2.3 (build abc)</div></",
+		},
+		{
+			name: "version from body",
+			rule: &Rule{
+				Name:     "a",
+				Matchers: []*Matcher{{Type: TypeTitle, Words: []string{"grafana"}}},
+				Version:  &Matcher{Type: TypeBody, Regex: []string{`Kibana ([\d.]+)`, `Grafana v([\d.]+)`}},
+			},
+			want:        true,
+			wantVersion: "10.2.3",
+		},
+		{
+			name: "version not found",
+			rule: &Rule{
+				Name:     "a",
+				Matchers: []*Matcher{{Type: TypeTitle, Words: []string{"grafana"}}},
+				Version:  &Matcher{Type: TypeHeader, Key: "x-version", Regex: []string{`([\d.]+)`}},
+			},
+			want:        true,
+			wantVersion: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp, ok := compiled(t, tt.rule).Match(testResult())
+			if ok != tt.want {
+				t.Fatalf("Match = %v, want %v", ok, tt.want)
+			}
+			if fp.Version != tt.wantVersion {
+				t.Errorf("Version = %q, want %q", fp.Version, tt.wantVersion)
+			}
+			if len(tt.wantEvidence) > 0 && fp.Evidence != tt.wantEvidence {
+				t.Errorf("Evidence = %q, want %q", fp.Evidence, tt.wantEvidence)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("中", 30) + "target" + strings.Repeat("文", 30)
+	start := strings.Index(text, "target")
+
+	got := snippet(text, start, start+len("target"))
+	if !strings.Contains(got, "target") || !strings.HasPrefix(got, "中") || !strings.HasSuffix(got, "文") {
+		t.Errorf("snippet = %q, want target with whole characters around it", got)
+	}
+	if len(got) > len("target")+2*(evidenceContext+2) {
+		t.Errorf("snippet = %q is longer than the context", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "grafana.yaml"), `
+name: Grafana
+matchers:
+  - type: title
+    words: [grafana]
+`)
+	write(t, filepath.Join(dir, "sub", "list.json"), `[
+  {"id": "nginx", "name": "nginx", "matchers": [{"type": "header", "key": "server", "regex": ["nginx"]}]},
+  {"name": "Express", "matchers": [{"type": "header", "key": "x-powered-by", "words": ["express"]}]}
+]`)
+	write(t, filepath.Join(dir, "README.md"), "not a rule")
+
+	rules, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+	if got, want := strings.Join(ids, ","), "grafana.yaml#1,nginx,list.json#2"; got != want {
+		t.Errorf("rule ids = %s, want %s", got, want)
+	}
+
+	if got := Match(rules, testResult()); len(got) != 3 {
+		t.Errorf("Match returned %d fingerprints, want 3", len(got))
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"null matcher yaml", "r.yaml", "name: a\nmatchers:\n  - null\n", "matcher 1 is empty"},
+		{"null matcher json", "r.json", `{"name": "a", "matchers": [{"type": "title", "words": ["a"]}, null]}`, "matcher 2 is empty"},
+		{"null rule", "r.json", `[null]`, "name is required"},
+		{"no name", "r.yaml", "matchers:\n  - type: title\n    words: [a]\n", "name is required"},
+		{"no matchers", "r.yaml", "name: a\n", "no matchers"},
+		{"bad condition", "r.yaml", "name: a\ncondition: xor\nmatchers:\n  - type: title\n    words: [a]\n", "unsupported condition"},
+		{"unknown type", "r.yaml", "name: a\nmatchers:\n  - type: cookie\n    words: [a]\n", "unsupported matcher type"},
+		{"no words", "r.yaml", "name: a\nmatchers:\n  - type: body\n", "requires words or regex"},
+		{"no status", "r.yaml", "name: a\nmatchers:\n  - type: status\n", "requires status"},
+		{"bad regex", "r.yaml", "name: a\nmatchers:\n  - type: body\n    regex: ['(']\n", "missing closing )"},
+		{"bad cert field", "r.yaml", "name: a\nmatchers:\n  - type: cert\n    field: owner\n    words: [a]\n", "unsupported cert field"},
+		{"version without group", "r.yaml", "name: a\nmatchers:\n  - type: title\n    words: [a]\nversion:\n  type: body\n  regex: ['v\\d+']\n", "no capture group"},
+		{"version status", "r.yaml", "name: a\nmatchers:\n  - type: title\n    words: [a]\nversion:\n  type: status\n  regex: ['(\\d+)']\n", "unsupported version type"},
+		{"invalid yaml", "r.yaml", "name: [a\n", "r.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tt.file)
+			write(t, file, tt.content)
+
+			_, err := Load(file)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func write(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
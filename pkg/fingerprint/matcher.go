@@ -0,0 +1,255 @@
+package fingerprint
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zan8in/pyxis/pkg/result"
+)
+
+const (
+	TypeTitle   = "title"
+	TypeBody    = "body"
+	TypeHeader  = "header"
+	TypeStatus  = "status"
+	TypeFavicon = "favicon"
+	TypeCert    = "cert"
+)
+
+// evidenceContext 证据片段在命中内容前后保留的字节数
+const evidenceContext = 20
+
+// Matcher 匹配结果中的一个部分：
+//
+//	title/body/header 按 words（不区分大小写的包含）或 regex 匹配，header 指定 key 时只匹配该响应头
+//	status 匹配状态码列表，favicon 匹配 favicon hash 列表
+//	cert 匹配证书字段，field 可选 subject/issuer/san/serial/sha256，未指定时匹配 subject、issuer 和 san
+//
+// 同一个 Matcher 内的多个值按 Condition 组合，默认 or
+type Matcher struct {
+	Type      string   `yaml:"type" json:"type"`
+	Key       string   `yaml:"key" json:"key"`
+	Field     string   `yaml:"field" json:"field"`
+	Condition string   `yaml:"condition" json:"condition"`
+	Words     []string `yaml:"words" json:"words"`
+	Regex     []string `yaml:"regex" json:"regex"`
+	Status    []int    `yaml:"status" json:"status"`
+	Hash      []string `yaml:"hash" json:"hash"`
+
+	// words 编译为不区分大小写的正则，和 regex 一起匹配，便于定位证据片段
+	regexes []*regexp.Regexp
+}
+
+func (m *Matcher) compile() error {
+	if err := m.normalize(); err != nil {
+		return err
+	}
+
+	switch m.Type {
+	case TypeTitle, TypeBody, TypeHeader, TypeCert:
+		if len(m.Words) == 0 && len(m.Regex) == 0 {
+			return fmt.Errorf("%s matcher requires words or regex", m.Type)
+		}
+	case TypeStatus:
+		if len(m.Status) == 0 {
+			return fmt.Errorf("status matcher requires status")
+		}
+	case TypeFavicon:
+		if len(m.Hash) == 0 {
+			return fmt.Errorf("favicon matcher requires hash")
+		}
+	default:
+		return fmt.Errorf("unsupported matcher type %q", m.Type)
+	}
+
+	for _, word := range m.Words {
+		m.regexes = append(m.regexes, regexp.MustCompile("(?i)"+regexp.QuoteMeta(word)))
+	}
+	return m.compileRegex()
+}
+
+// compileVersion 编译版本提取器，只支持文本类型和带分组的 regex
+func (m *Matcher) compileVersion() error {
+	if err := m.normalize(); err != nil {
+		return err
+	}
+
+	switch m.Type {
+	case TypeTitle, TypeBody, TypeHeader, TypeCert:
+	default:
+		return fmt.Errorf("unsupported version type %q", m.Type)
+	}
+	if len(m.Regex) == 0 {
+		return fmt.Errorf("version requires regex")
+	}
+
+	if err := m.compileRegex(); err != nil {
+		return err
+	}
+	for _, re := range m.regexes {
+		if re.NumSubexp() == 0 {
+			return fmt.Errorf("version regex %q has no capture group", re.String())
+		}
+	}
+	return nil
+}
+
+func (m *Matcher) normalize() error {
+	m.Type = strings.ToLower(strings.TrimSpace(m.Type))
+	m.Key = strings.ToLower(strings.TrimSpace(m.Key))
+	m.Field = strings.ToLower(strings.TrimSpace(m.Field))
+
+	switch m.Field {
+	case "", "subject", "issuer", "san", "serial", "sha256":
+	default:
+		return fmt.Errorf("unsupported cert field %q", m.Field)
+	}
+
+	var err error
+	m.Condition, err = condition(m.Condition)
+	return err
+}
+
+func (m *Matcher) compileRegex() error {
+	for _, expr := range m.Regex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return err
+		}
+		m.regexes = append(m.regexes, re)
+	}
+	return nil
+}
+
+// Match 判断结果是否命中该 Matcher，命中时返回 "类型: 片段" 形式的证据
+func (m *Matcher) Match(rst *result.HostResult) (bool, string) {
+	var evidence []string
+
+	switch m.Type {
+	case TypeStatus:
+		ok := m.matchEach(len(m.Status), func(i int) bool {
+			if rst.StatusCode != m.Status[i] {
+				return false
+			}
+			evidence = append(evidence, strconv.Itoa(rst.StatusCode))
+			return true
+		})
+		return ok, m.evidence(evidence)
+
+	case TypeFavicon:
+		ok := len(rst.FaviconHash) > 0 && m.matchEach(len(m.Hash), func(i int) bool {
+			if rst.FaviconHash != m.Hash[i] {
+				return false
+			}
+			evidence = append(evidence, rst.FaviconHash)
+			return true
+		})
+		return ok, m.evidence(evidence)
+	}
+
+	parts := m.parts(rst)
+	if len(parts) == 0 {
+		return false, ""
+	}
+
+	text := strings.Join(parts, "\n")
+	ok := m.matchEach(len(m.regexes), func(i int) bool {
+		loc := m.regexes[i].FindStringIndex(text)
+		if loc == nil {
+			return false
+		}
+		evidence = append(evidence, snippet(text, loc[0], loc[1]))
+		return true
+	})
+	return ok, m.evidence(evidence)
+}
+
+// Extract 使用版本提取器从结果中提取版本号，第一个命中的 regex 分组生效
+func (m *Matcher) Extract(rst *result.HostResult) string {
+	text := strings.Join(m.parts(rst), "\n")
+	if len(text) == 0 {
+		return ""
+	}
+	for _, re := range m.regexes {
+		if match := re.FindStringSubmatch(text); len(match) > 1 && len(match[1]) > 0 {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+func (m *Matcher) evidence(values []string) string {
+	label := m.Type
+	if len(m.Key) > 0 {
+		label += "." + m.Key
+	} else if len(m.Field) > 0 {
+		label += "." + m.Field
+	}
+	return label + ": " + strings.Join(values, " | ")
+}
+
+// snippet 截取命中内容及其前后少量上下文，按 UTF-8 字符边界对齐并去掉换行
+func snippet(text string, start, end int) string {
+	from := max(0, start-evidenceContext)
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	to := min(len(text), end+evidenceContext)
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+	return strings.Join(strings.Fields(text[from:to]), " ")
+}
+
+// parts 返回 Matcher 要匹配的文本
+func (m *Matcher) parts(rst *result.HostResult) []string {
+	switch m.Type {
+	case TypeTitle:
+		return []string{rst.Title}
+	case TypeBody:
+		return []string{rst.Body}
+	case TypeHeader:
+		if len(m.Key) > 0 {
+			if value, ok := rst.Headers[m.Key]; ok {
+				return []string{value}
+			}
+			return nil
+		}
+		return []string{string(rst.RawHeader)}
+	case TypeCert:
+		if rst.TLSInfo == nil {
+			return nil
+		}
+		switch m.Field {
+		case "subject":
+			return []string{rst.TLSInfo.SubjectCN}
+		case "issuer":
+			return []string{rst.TLSInfo.Issuer}
+		case "san":
+			return rst.TLSInfo.SANs
+		case "serial":
+			return []string{rst.TLSInfo.Serial}
+		case "sha256":
+			return []string{rst.TLSInfo.SHA256}
+		}
+		return append([]string{rst.TLSInfo.SubjectCN, rst.TLSInfo.Issuer}, rst.TLSInfo.SANs...)
+	}
+	return nil
+}
+
+// matchEach 按 Condition 组合 n 个值的匹配结果
+func (m *Matcher) matchEach(n int, match func(i int) bool) bool {
+	for i := 0; i < n; i++ {
+		matched := match(i)
+		if matched && m.Condition == ConditionOr {
+			return true
+		}
+		if !matched && m.Condition == ConditionAnd {
+			return false
+		}
+	}
+	return m.Condition == ConditionAnd
+}
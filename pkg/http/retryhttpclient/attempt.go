@@ -0,0 +1,56 @@
+package retryhttpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Attempt 是一次 HTTP 请求尝试的结果，Err 为 nil 时 StatusCode 和 Latency 有效
+type Attempt struct {
+	Addr       string        // 连接的 host:port，虚拟主机枚举时为指定的地址
+	Latency    time.Duration // 从发出请求到收到响应头的时间
+	StatusCode int
+	Err        error
+}
+
+// observedTransport 在每次请求尝试后调用 onAttempt，重试和跳转的每一跳都单独记录
+type observedTransport struct {
+	next      http.RoundTripper
+	onAttempt func(Attempt)
+}
+
+func (t *observedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	// 主动取消的请求与目标和网络状况无关
+	if errors.Is(err, context.Canceled) {
+		return resp, err
+	}
+
+	attempt := Attempt{Addr: attemptAddr(req), Latency: time.Since(start), Err: err}
+	if resp != nil {
+		attempt.StatusCode = resp.StatusCode
+	}
+	t.onAttempt(attempt)
+
+	return resp, err
+}
+
+func attemptAddr(req *http.Request) string {
+	port := req.URL.Port()
+	if len(port) == 0 {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	addr := net.JoinHostPort(req.URL.Hostname(), port)
+	if d, ok := req.Context().Value(dialKey{}).(dialAddr); ok && d.from == addr {
+		return d.to
+	}
+	return addr
+}
@@ -2,63 +2,375 @@ package retryhttpclient
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptrace"
+	"net/url"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/zan8in/pyxis/pkg/proxypool"
 	"github.com/zan8in/pyxis/pkg/result"
-	"github.com/zan8in/pyxis/pkg/util/randutil"
 	"github.com/zan8in/pyxis/pkg/util/stringutil"
 	"github.com/zan8in/retryablehttp"
 )
 
 var (
 	RedirectClient *retryablehttp.Client
+
+	defaultClient *Client
 )
 
 const maxDefaultBody = 2 * 1024 * 1024
 
+const (
+	RedirectAll      = "all"
+	RedirectSameHost = "same-host"
+	RedirectNone     = "none"
+)
+
+const maxRedirects = 10
+
 type Options struct {
-	Timeout int
-	Retries int
-	Proxy   string
+	Timeout  int
+	Retries  int
+	Proxy    string // comma separated proxies or a file of proxies, ignored when ProxyPool is set
+	Redirect string // redirect policy: all, same-host or none
+
+	ProxyPool *proxypool.Pool // ProxyPool rotates proxies per request, it can be shared with other checkers
+
+	MaxHostConns int // concurrent requests per host, 0 is unlimited
+	MaxIPConns   int // concurrent requests per ip, 0 is unlimited
+
+	OnAttempt func(Attempt) // called after every HTTP attempt, including retries, redirects and favicon requests
+
+	// custom request, header, cookie, body and user agent support {{host}} placeholders
+	Method    string   // method of the probes, default GET, favicon requests always use GET
+	Body      string   // body of the probes
+	Headers   []string // "Name: value" headers sent with every request
+	Cookie    string   // cookie sent with every request
+	UserAgent string   // user agent, default a random one per request
 }
 
-func Init(options *Options) (err error) {
-	po := &retryablehttp.DefaultPoolOptions
-	po.Proxy = options.Proxy
-	po.Timeout = options.Timeout
-	po.Retries = options.Retries
-	po.EnableRedirect(retryablehttp.FollowAllRedirect)
+// Client 是独立的 HTTP 客户端，代理、超时和跳转策略只属于自身，
+// 同一进程中的多个 Client 互不影响
+type Client struct {
+	client *retryablehttp.Client
 
-	retryablehttp.InitClientPool(po)
+	hostLimiter *keyedLimiter
+	ipLimiter   *keyedLimiter
+	resolved    *resolveCache // 主机名到 IP 的本地解析结果，供 ipLimiter 使用
+
+	method    string
+	body      string
+	headers   []header
+	cookie    string
+	userAgent string
+}
 
-	if RedirectClient, err = retryablehttp.GetPool(po); err != nil {
+// Init 初始化包级默认客户端，供 Get/GetContext 使用
+func Init(options *Options) (err error) {
+	if defaultClient, err = New(options); err != nil {
 		return err
 	}
-
+	RedirectClient = defaultClient.client
 	return nil
 }
 
+// New 按配置创建独立的客户端，不读写 retryablehttp 的全局连接池和代理设置
+func New(options *Options) (*Client, error) {
+	headers, err := parseHeaders(options.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	method := strings.ToUpper(strings.TrimSpace(options.Method))
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+
+	pool := options.ProxyPool
+	if pool == nil && len(options.Proxy) > 0 {
+		if pool, err = proxypool.New(options.Proxy, proxypool.ModeRoundRobin, 0); err != nil {
+			return nil, err
+		}
+	}
+	transport := newTransport(pool)
+
+	timeout := time.Duration(options.Timeout) * time.Second
+	var roundTripper http.RoundTripper = transport
+	if options.OnAttempt != nil {
+		roundTripper = &observedTransport{next: transport, onAttempt: options.OnAttempt}
+	}
+
+	httpClient := &http.Client{
+		Transport:     roundTripper,
+		Timeout:       timeout,
+		CheckRedirect: checkRedirect(redirectFlow(options.Redirect)),
+	}
+
+	retryOptions := retryablehttp.DefaultOptionsSpraying
+	retryOptions.RetryWaitMax = 10 * time.Second
+	retryOptions.RetryMax = options.Retries
+	retryOptions.Timeout = timeout
+	retryOptions.HttpClient = httpClient
+
+	client := retryablehttp.NewClient(retryOptions)
+	if client == nil {
+		return nil, fmt.Errorf("could not create http client")
+	}
+	client.CheckRetry = retryablehttp.HostSprayRetryPolicy()
+	if pool != nil {
+		client.CheckRetry = checkProxy(pool, client.CheckRetry)
+	}
+
+	// 代理解析目标（如 socks5h）时本地解析会泄露目标，且连接的不是本地解析的 IP，不按 IP 限制
+	var ipLimiter *keyedLimiter
+	if pool == nil {
+		ipLimiter = newKeyedLimiter(options.MaxIPConns)
+	}
+
+	return &Client{
+		client:      client,
+		hostLimiter: newKeyedLimiter(options.MaxHostConns),
+		ipLimiter:   ipLimiter,
+		resolved:    newResolveCache(resolveTTL, resolveMaxSize),
+
+		method:    method,
+		body:      options.Body,
+		headers:   headers,
+		cookie:    options.Cookie,
+		userAgent: options.UserAgent,
+	}, nil
+}
+
+// newTransport 创建不复用连接的传输层，配置了代理池时每个请求（包括重试）从池中选择代理，
+// HTTP 和 SOCKS5 代理都由 Transport.Proxy 处理
+func newTransport(pool *proxypool.Pool) *http.Transport {
+	tlsConfig := &tls.Config{
+		Renegotiation:      tls.RenegotiateOnceAsClient,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS10,
+		CipherSuites: []uint16{
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if d, ok := ctx.Value(dialKey{}).(dialAddr); ok && d.from == addr {
+				addr = d.to
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+		MaxIdleConnsPerHost: -1,
+		TLSClientConfig:     tlsConfig,
+		DisableKeepAlives:   true,
+	}
+
+	if pool != nil {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			p := pool.Next()
+			if used, ok := req.Context().Value(proxyKey{}).(*atomic.Pointer[proxypool.Proxy]); ok {
+				used.Store(p)
+			}
+			return p.URL, nil
+		}
+	}
+
+	return transport
+}
+
+type dialKey struct{}
+
+// dialAddr 将到 from 的连接改为连接 to，请求的 Host 和 SNI 保持不变
+type dialAddr struct {
+	from string
+	to   string
+}
+
+// WithDialAddr 使 ctx 中对 target 主机的请求连接 addr（ip:port），Host 和 SNI 仍为 target 的主机名，
+// 用于虚拟主机枚举。跳转到其他主机时不受影响，配置代理时由代理连接目标，addr 不生效
+func WithDialAddr(ctx context.Context, target, addr string) (context.Context, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return ctx, err
+	}
+	port := u.Port()
+	if len(port) == 0 {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return context.WithValue(ctx, dialKey{}, dialAddr{from: net.JoinHostPort(u.Hostname(), port), to: addr}), nil
+}
+
+type proxyKey struct{}
+
+// withProxy 在上下文中记录本次请求使用的代理，供 checkProxy 更新代理状态
+func withProxy(ctx context.Context) context.Context {
+	return context.WithValue(ctx, proxyKey{}, &atomic.Pointer[proxypool.Proxy]{})
+}
+
+// checkProxy 在每次尝试后更新代理状态：代理错误标记为不可用，重试时会换用其他代理。
+// SOCKS 代理转发的目标错误（端口关闭、主机不可达等）说明代理可用，不标记
+func checkProxy(pool *proxypool.Pool, next retryablehttp.CheckRetry) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if used, ok := ctx.Value(proxyKey{}).(*atomic.Pointer[proxypool.Proxy]); ok {
+			if p := used.Swap(nil); p != nil {
+				switch {
+				case err == nil:
+					pool.MarkOK(p)
+				case result.Classify(err) == result.FailureProxy:
+					pool.MarkFailed(p)
+				}
+			}
+		}
+		return next(ctx, resp, err)
+	}
+}
+
+// IsRedirectPolicy checks if the redirect policy is supported
+func IsRedirectPolicy(policy string) bool {
+	switch policy {
+	case "", RedirectAll, RedirectSameHost, RedirectNone:
+		return true
+	}
+	return false
+}
+
+func redirectFlow(policy string) retryablehttp.RedirectFlow {
+	switch policy {
+	case RedirectNone:
+		return retryablehttp.DontFollowRedirect
+	case RedirectSameHost:
+		return retryablehttp.FollowSameHostRedirect
+	default:
+		return retryablehttp.FollowAllRedirect
+	}
+}
+
+type redirectsKey struct{}
+
+// withRedirects 在上下文中挂载跳转记录，供 checkRedirect 写入
+func withRedirects(ctx context.Context) (context.Context, *[]result.Redirect) {
+	redirects := make([]result.Redirect, 0)
+	return context.WithValue(ctx, redirectsKey{}, &redirects), &redirects
+}
+
+// checkRedirect 按跳转策略决定是否继续跟随，并把每一跳记录到请求上下文中
+func checkRedirect(flow retryablehttp.RedirectFlow) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if flow == retryablehttp.DontFollowRedirect {
+			return http.ErrUseLastResponse
+		}
+		if flow == retryablehttp.FollowSameHostRedirect && req.URL.Host != via[0].URL.Host {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return http.ErrUseLastResponse
+		}
+
+		if redirects, ok := req.Context().Value(redirectsKey{}).(*[]result.Redirect); ok && req.Response != nil {
+			hop := result.Redirect{
+				URL:        via[len(via)-1].URL.String(),
+				StatusCode: req.Response.StatusCode,
+				Location:   req.Response.Header.Get("Location"),
+			}
+			// 重试时会从头再走一遍跳转，按跳数覆盖而不是追加
+			*redirects = append((*redirects)[:len(via)-1], hop)
+		}
+		return nil
+	}
+}
+
 func Get(target string) (result.HostResult, error) {
+	return GetContext(context.Background(), target)
+}
+
+// GetContext 使用 Init 创建的默认客户端请求 target
+func GetContext(ctx context.Context, target string) (result.HostResult, error) {
+	if defaultClient == nil {
+		return result.HostResult{}, fmt.Errorf("retryhttpclient is not initialized")
+	}
+	return defaultClient.GetContext(ctx, target)
+}
+
+func (c *Client) Get(target string) (result.HostResult, error) {
+	return c.GetContext(context.Background(), target)
+}
+
+// GetContext 与 Get 相同，ctx 取消时请求（包括重试和跳转）立即中止
+func (c *Client) GetContext(ctx context.Context, target string) (result.HostResult, error) {
+	return c.do(ctx, http.MethodGet, target, "")
+}
+
+// ProbeContext 使用配置的请求方法和请求体探测 target，用于目标和路径扫描
+func (c *Client) ProbeContext(ctx context.Context, target string) (result.HostResult, error) {
+	return c.do(ctx, c.method, target, c.body)
+}
+
+func (c *Client) do(ctx context.Context, method, target, body string) (result.HostResult, error) {
 	var (
 		err    error
 		result result.HostResult
 	)
 
-	timeoutDuration := time.Duration(RedirectClient.HTTPClient.Timeout)
-	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
+	// 等待主机和 IP 槽位的时间不计入请求超时
+	release, err := c.acquireConns(ctx, target)
+	if err != nil {
+		return result, err
+	}
+	defer release()
+
+	timeoutDuration := c.client.HTTPClient.Timeout
+	ctx, cancel := context.WithTimeout(ctx, timeoutDuration)
 	defer cancel()
 
-	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	ctx, redirects := withRedirects(ctx)
+	ctx = withProxy(ctx)
+
+	var reqBody any
+	if len(body) > 0 {
+		u, err := url.Parse(target)
+		if err != nil {
+			return result, err
+		}
+		reqBody = []byte(expand(body, u))
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, method, target, reqBody)
 	if err != nil {
 		return result, err
 	}
 
-	req.Header.Add("User-Agent", randutil.RandomUA())
+	c.setHeaders(req)
+	if reqBody != nil && len(req.Header.Get("Content-Type")) == 0 {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
 
 	// latency
 	var milliseconds int64
@@ -69,7 +381,7 @@ func Get(target string) (result.HostResult, error) {
 	}
 	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &trace))
 
-	resp, err := RedirectClient.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		if resp != nil {
 			resp.Body.Close()
@@ -86,6 +398,12 @@ func Get(target string) (result.HostResult, error) {
 
 	// 设置基本字段
 	result.FullUrl = target
+	result.FinalUrl = target
+	if resp.Request != nil {
+		result.FinalUrl = resp.Request.URL.String()
+	}
+	result.Redirects = *redirects
+	result.TLSInfo = tlsInfo(resp.TLS)
 	result.StatusCode = resp.StatusCode
 	result.ResponseTime = milliseconds
 	result.ContentLength = int64(len(respBody))
@@ -129,6 +447,38 @@ func Get(target string) (result.HostResult, error) {
 	return result, nil
 }
 
+// tlsInfo 提取叶子证书和握手信息
+func tlsInfo(state *tls.ConnectionState) *result.TLSInfo {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	cert := state.PeerCertificates[0]
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	info := &result.TLSInfo{
+		SubjectCN:   cert.Subject.CommonName,
+		Issuer:      cert.Issuer.CommonName,
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Serial:      cert.SerialNumber.Text(16),
+		SHA256:      hex.EncodeToString(fingerprint[:]),
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+	}
+	if len(info.Issuer) == 0 {
+		info.Issuer = cert.Issuer.String()
+	}
+
+	info.SANs = append(info.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	return info
+}
+
 var RegexTitle = regexp.MustCompile(`(?i:)<title>(.*?)</title>`)
 
 func getTitle(body string) string {
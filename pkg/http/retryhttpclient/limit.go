@@ -0,0 +1,197 @@
+package retryhttpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	resolveTTL     = time.Minute // 解析结果的有效期，目标的 DNS 变化时不会一直按旧 IP 限制
+	resolveMaxSize = 4096        // 最多缓存的主机名数
+)
+
+// keyedLimiter 按 key 限制同时进行的请求数，limit <= 0 时不限制。
+// 空闲的 key 会被删除，目标很多时内存不会随之增长
+type keyedLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	slots map[string]*keySlot
+}
+
+type keySlot struct {
+	ch   chan struct{}
+	refs int
+}
+
+func newKeyedLimiter(limit int) *keyedLimiter {
+	return &keyedLimiter{
+		limit: limit,
+		slots: make(map[string]*keySlot),
+	}
+}
+
+// acquire 等待 key 的空闲槽位，返回释放函数，ctx 取消时返回 ctx 的错误
+func (l *keyedLimiter) acquire(ctx context.Context, key string) (func(), error) {
+	if l == nil || l.limit <= 0 || len(key) == 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	slot, ok := l.slots[key]
+	if !ok {
+		slot = &keySlot{ch: make(chan struct{}, l.limit)}
+		l.slots[key] = slot
+	}
+	slot.refs++
+	l.mu.Unlock()
+
+	select {
+	case slot.ch <- struct{}{}:
+		return func() {
+			<-slot.ch
+			l.unref(key, slot)
+		}, nil
+	case <-ctx.Done():
+		l.unref(key, slot)
+		return nil, ctx.Err()
+	}
+}
+
+func (l *keyedLimiter) unref(key string, slot *keySlot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slot.refs--
+	if slot.refs == 0 {
+		delete(l.slots, key)
+	}
+}
+
+// acquireConns 按目标的主机名和 IP 占用并发槽位，先主机后 IP，顺序固定避免互相等待。
+// IP 在本地解析并在客户端内缓存，解析失败时只按主机名限制，由请求本身报告解析错误
+func (c *Client) acquireConns(ctx context.Context, target string) (func(), error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return func() {}, nil
+	}
+	host := u.Hostname()
+
+	releaseHost, err := c.hostLimiter.acquire(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ipLimiter == nil || c.ipLimiter.limit <= 0 {
+		return releaseHost, nil
+	}
+
+	// 虚拟主机枚举时连接的是指定的地址
+	ip := host
+	if d, ok := ctx.Value(dialKey{}).(dialAddr); ok {
+		ip, _, _ = net.SplitHostPort(d.to)
+	}
+	if net.ParseIP(ip) == nil {
+		if ip = c.resolve(ctx, host); len(ip) == 0 {
+			return releaseHost, nil
+		}
+	}
+
+	releaseIP, err := c.ipLimiter.acquire(ctx, ip)
+	if err != nil {
+		releaseHost()
+		return nil, err
+	}
+
+	return func() {
+		releaseIP()
+		releaseHost()
+	}, nil
+}
+
+// resolve 返回主机名的第一个 IP，同一主机的多个端口和路径在有效期内只解析一次。
+// 域名不存在时缓存空结果，超时等临时错误不缓存
+func (c *Client) resolve(ctx context.Context, host string) string {
+	if ip, ok := c.resolved.get(host); ok {
+		return ip
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			c.resolved.set(host, "")
+		}
+		return ""
+	}
+	if len(addrs) == 0 {
+		return ""
+	}
+
+	ip := addrs[0].IP.String()
+	c.resolved.set(host, ip)
+	return ip
+}
+
+// resolveCache 是带有效期和容量上限的解析缓存，扫描大量域名时内存不会随之增长
+type resolveCache struct {
+	ttl     time.Duration
+	maxSize int
+
+	mu      sync.Mutex
+	entries map[string]resolveEntry
+}
+
+type resolveEntry struct {
+	ip      string
+	expires time.Time
+}
+
+func newResolveCache(ttl time.Duration, maxSize int) *resolveCache {
+	return &resolveCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[string]resolveEntry),
+	}
+}
+
+func (c *resolveCache) get(host string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[host]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, host)
+		return "", false
+	}
+	return e.ip, true
+}
+
+// set 缓存解析结果，已满时先清理过期的条目，仍然满时随机淘汰一个
+func (c *resolveCache) set(host, ip string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[host]; !ok && len(c.entries) >= c.maxSize {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.maxSize {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[host] = resolveEntry{ip: ip, expires: now.Add(c.ttl)}
+}
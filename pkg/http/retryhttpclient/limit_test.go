@@ -0,0 +1,31 @@
+package retryhttpclient
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestResolveCacheBounded(t *testing.T) {
+	c := newResolveCache(time.Minute, 10)
+	for i := 0; i < 100; i++ {
+		c.set(fmt.Sprintf("host%d.test", i), "127.0.0.1")
+	}
+	if len(c.entries) > 10 {
+		t.Fatalf("cache holds %d entries, want at most 10", len(c.entries))
+	}
+	if ip, ok := c.get("host99.test"); !ok || ip != "127.0.0.1" {
+		t.Errorf("latest entry missing: %q %v", ip, ok)
+	}
+}
+
+func TestResolveCacheExpires(t *testing.T) {
+	c := newResolveCache(-time.Second, 10)
+	c.set("a.test", "127.0.0.1")
+	if _, ok := c.get("a.test"); ok {
+		t.Error("expired entry returned")
+	}
+	if len(c.entries) != 0 {
+		t.Errorf("expired entry not removed: %d entries", len(c.entries))
+	}
+}
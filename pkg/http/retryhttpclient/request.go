@@ -0,0 +1,82 @@
+package retryhttpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/zan8in/pyxis/pkg/util/randutil"
+	"github.com/zan8in/retryablehttp"
+)
+
+// header 是一个自定义请求头，值中可以包含占位符
+type header struct {
+	name  string
+	value string
+}
+
+// parseHeaders 解析 "Name: value" 形式的请求头
+func parseHeaders(list []string) ([]header, error) {
+	var headers []header
+	for _, item := range list {
+		name, value, ok := strings.Cut(item, ":")
+		name = strings.TrimSpace(name)
+		if !ok || len(name) == 0 {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", item)
+		}
+		headers = append(headers, header{name: http.CanonicalHeaderKey(name), value: strings.TrimSpace(value)})
+	}
+	return headers, nil
+}
+
+// expand 替换占位符：{{host}} 为 host:port（端口未指定时不含端口），{{hostname}} 为主机名，
+// {{port}} 为端口（未指定时为协议默认端口），{{scheme}} 为协议
+func expand(s string, u *url.URL) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+
+	port := u.Port()
+	if len(port) == 0 {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return strings.NewReplacer(
+		"{{host}}", u.Host,
+		"{{hostname}}", u.Hostname(),
+		"{{port}}", port,
+		"{{scheme}}", u.Scheme,
+	).Replace(s)
+}
+
+// setHeaders 设置 User-Agent、Cookie 和自定义请求头，Host 请求头写入 req.Host。
+// 虚拟主机枚举的请求以候选主机名作为 Host，不被 -H Host 覆盖
+func (c *Client) setHeaders(req *retryablehttp.Request) {
+	u := req.Request.URL
+	_, vhost := req.Context().Value(dialKey{}).(dialAddr)
+
+	if len(c.userAgent) > 0 {
+		req.Header.Set("User-Agent", expand(c.userAgent, u))
+	} else {
+		req.Header.Set("User-Agent", randutil.RandomUA())
+	}
+
+	if len(c.cookie) > 0 {
+		req.Header.Set("Cookie", expand(c.cookie, u))
+	}
+
+	for _, h := range c.headers {
+		value := expand(h.value, u)
+		if h.name == "Host" {
+			if !vhost {
+				req.Host = value
+			}
+			continue
+		}
+		req.Header.Add(h.name, value)
+	}
+}
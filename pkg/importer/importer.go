@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatTXT     = "txt"
+	FormatNmap    = "nmap"
+	FormatMasscan = "masscan"
+	FormatNaabu   = "naabu"
+)
+
+// IsSupported checks if the input format can be parsed
+func IsSupported(format string) bool {
+	switch strings.ToLower(format) {
+	case "", FormatTXT, FormatNmap, FormatMasscan, FormatNaabu:
+		return true
+	}
+	return false
+}
+
+// Parse reads the scan results from reader in the given format and calls fn
+// with every target found, targets are emitted while the input is still being read
+func Parse(format string, reader io.Reader, fn func(target string)) error {
+	switch strings.ToLower(format) {
+	case "", FormatTXT:
+		return parseLines(reader, func(line string) error {
+			fn(line)
+			return nil
+		})
+	case FormatNmap:
+		return ParseNmap(reader, fn)
+	case FormatMasscan:
+		return ParseMasscan(reader, fn)
+	case FormatNaabu:
+		return ParseNaabu(reader, fn)
+	}
+	return fmt.Errorf("unsupported input format: %s", format)
+}
+
+// Target builds a host:port target, an explicit scheme is prepended when the
+// service is known to speak http or https so the other probe can be skipped
+func Target(host string, port int, scheme string) string {
+	target := net.JoinHostPort(host, strconv.Itoa(port))
+	if len(scheme) > 0 {
+		return scheme + "://" + target
+	}
+	return target
+}
+
+func parseLines(reader io.Reader, fn func(line string) error) error {
+	s := bufio.NewScanner(reader)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseFile 解析 testdata 中的文件，返回所有目标
+func parseFile(t *testing.T, format, name string) ([]string, error) {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var targets []string
+	err = Parse(format, f, func(target string) {
+		targets = append(targets, target)
+	})
+	return targets, err
+}
+
+// parseString 解析 input，返回解析错误之前得到的目标和错误
+func parseString(format, input string) ([]string, error) {
+	var targets []string
+	err := Parse(format, strings.NewReader(input), func(target string) {
+		targets = append(targets, target)
+	})
+	return targets, err
+}
+
+func TestParseFiles(t *testing.T) {
+	tests := []struct {
+		format string
+		file   string
+		want   []string
+	}{
+		{FormatNmap, "nmap.xml", []string{
+			"http://example.com:80",
+			"https://example.com:443",
+			"https://example.com:8443",
+			"example.com:22",
+			"192.168.1.1:8000",
+			"https://[2001:db8::1]:443",
+		}},
+		{FormatMasscan, "masscan.json", []string{
+			"10.0.0.1:80",
+			"10.0.0.1:443",
+			"[2001:db8::2]:8443",
+		}},
+		{FormatMasscan, "masscan.txt", []string{
+			"10.0.0.1:80",
+			"10.0.0.1:443",
+			"[2001:db8::2]:8443",
+		}},
+		{FormatNaabu, "naabu.json", []string{
+			"example.com:80",
+			"https://example.com:443",
+			"10.0.0.5:8080",
+			"https://[2001:db8::3]:8443",
+			"scanme.example.org:8000",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := parseFile(t, tt.format, tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("targets = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   []string // 出错前已得到的目标
+	}{
+		{"nmap truncated", FormatNmap, `<nmaprun><host><address addr="10.0.0.1" addrtype="ipv4"/><ports><port protocol="tcp" portid="80"><state state="open"/></port></ports></host><host><address`, []string{"10.0.0.1:80"}},
+		{"nmap not xml", FormatNmap, "10.0.0.1:80\n<", nil},
+		{"masscan bad json", FormatMasscan, "open tcp 80 10.0.0.1 1\n{\"ip\": \"10.0.0.2\", \"ports\": [\n", []string{"10.0.0.1:80"}},
+		{"naabu bad json", FormatNaabu, "{\"ip\":\"10.0.0.1\",\"port\":80}\n{\"ip\":\n", []string{"10.0.0.1:80"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseString(tt.format, tt.input)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("targets before the error = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMasscanRepeatedHost(t *testing.T) {
+	// 只在同一 IP 的连续记录内去重，之后再次出现的端口重新输出
+	input := "open tcp 80 10.0.0.1 1\nopen tcp 80 10.0.0.1 1\nopen tcp 80 10.0.0.2 2\nopen tcp 80 10.0.0.1 3\n"
+	got, err := parseString(FormatMasscan, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.1:80"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %q, want %q", got, want)
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	if IsSupported("xml") {
+		t.Error("xml should not be supported")
+	}
+	if _, err := parseString("xml", "10.0.0.1"); err == nil {
+		t.Error("expected an error for unsupported format")
+	}
+}
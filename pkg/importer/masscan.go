@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type masscanRecord struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port   int    `json:"port"`
+		Proto  string `json:"proto"`
+		Status string `json:"status"`
+	} `json:"ports"`
+}
+
+// ParseMasscan parses masscan -oJ (one record per line) and -oL list output.
+// Duplicates are only dropped within consecutive records of the same ip so memory
+// stays bounded on huge files, a port reported again later is emitted again
+func ParseMasscan(reader io.Reader, fn func(target string)) error {
+	var (
+		lastIP string
+		seen   = make(map[int]struct{})
+	)
+	emit := func(ip string, port int) {
+		if ip != lastIP {
+			lastIP = ip
+			clear(seen)
+		}
+		// masscan 会重复报告同一端口
+		if _, ok := seen[port]; ok {
+			return
+		}
+		seen[port] = struct{}{}
+		fn(Target(ip, port, ""))
+	}
+
+	return parseLines(reader, func(line string) error {
+		switch {
+		case strings.HasPrefix(line, "{"):
+			var record masscanRecord
+			if err := json.Unmarshal([]byte(strings.TrimSuffix(line, ",")), &record); err != nil {
+				return err
+			}
+			for _, port := range record.Ports {
+				if port.Proto == "tcp" && port.Status == "open" {
+					emit(record.IP, port.Port)
+				}
+			}
+		case strings.HasPrefix(line, "open "):
+			// open tcp 80 1.2.3.4 1690000000
+			fields := strings.Fields(line)
+			if len(fields) < 4 || fields[1] != "tcp" {
+				return nil
+			}
+			if port, err := strconv.Atoi(fields[2]); err == nil {
+				emit(fields[3], port)
+			}
+		}
+		return nil
+	})
+}
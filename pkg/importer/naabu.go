@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+type naabuRecord struct {
+	Host     string `json:"host"`
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	TLS      bool   `json:"tls"`
+}
+
+// ParseNaabu parses naabu -json output, plain host:port lines are passed through
+func ParseNaabu(reader io.Reader, fn func(target string)) error {
+	return parseLines(reader, func(line string) error {
+		if !strings.HasPrefix(line, "{") {
+			fn(line)
+			return nil
+		}
+
+		var record naabuRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return err
+		}
+		if record.Port == 0 || (len(record.Protocol) > 0 && record.Protocol != "tcp") {
+			return nil
+		}
+
+		host := record.Host
+		if len(host) == 0 {
+			host = record.IP
+		}
+		if len(host) == 0 {
+			return nil
+		}
+
+		scheme := ""
+		if record.TLS {
+			scheme = "https"
+		}
+		fn(Target(host, record.Port, scheme))
+		return nil
+	})
+}
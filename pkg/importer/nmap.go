@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type nmapHost struct {
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []struct {
+		Protocol string `xml:"protocol,attr"`
+		PortID   string `xml:"portid,attr"`
+		State    struct {
+			State string `xml:"state,attr"`
+		} `xml:"state"`
+		Service struct {
+			Name   string `xml:"name,attr"`
+			Tunnel string `xml:"tunnel,attr"`
+		} `xml:"service"`
+	} `xml:"ports>port"`
+}
+
+// ParseNmap parses nmap -oX output, <host> elements are decoded one at a time
+func ParseNmap(reader io.Reader, fn func(target string)) error {
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "host" {
+			continue
+		}
+
+		var host nmapHost
+		if err := decoder.DecodeElement(&host, &start); err != nil {
+			return err
+		}
+
+		name := host.name()
+		if len(name) == 0 {
+			continue
+		}
+
+		for _, port := range host.Ports {
+			if port.Protocol != "tcp" || port.State.State != "open" {
+				continue
+			}
+			portID, err := strconv.Atoi(port.PortID)
+			if err != nil {
+				continue
+			}
+			fn(Target(name, portID, nmapScheme(port.Service.Name, port.Service.Tunnel)))
+		}
+	}
+}
+
+// name prefers the hostname given on the nmap command line so vhosts keep working
+func (host *nmapHost) name() string {
+	for _, hostname := range host.Hostnames {
+		if hostname.Type == "user" && len(hostname.Name) > 0 {
+			return hostname.Name
+		}
+	}
+	for _, address := range host.Addresses {
+		if address.AddrType == "ipv4" || address.AddrType == "ipv6" {
+			return address.Addr
+		}
+	}
+	return ""
+}
+
+// nmapScheme turns the service detection result into a scheme hint,
+// ssl/http is reported by nmap as name="http" tunnel="ssl"
+func nmapScheme(name, tunnel string) string {
+	name = strings.ToLower(name)
+	switch {
+	case name == "https" || name == "https-alt" || (tunnel == "ssl" && strings.HasPrefix(name, "http")):
+		return "https"
+	case tunnel == "" && strings.HasPrefix(name, "http"):
+		return "http"
+	}
+	return ""
+}
@@ -0,0 +1,207 @@
+package proxypool
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/zan8in/gologger"
+	"github.com/zan8in/pyxis/pkg/util/fileutil"
+)
+
+const (
+	ModeRoundRobin = "round-robin"
+	ModeRandom     = "random"
+)
+
+// DefaultRetryAfter 代理被标记为不可用后，重新尝试前等待的时间
+const DefaultRetryAfter = 30 * time.Second
+
+// Proxy 是池中的一个代理，请求失败时在一段时间内不再被选中
+type Proxy struct {
+	URL *url.URL
+
+	downUntil atomic.Int64 // 不可用截止时间（UnixNano），0 表示可用
+}
+
+func (p *Proxy) String() string {
+	return p.URL.Redacted()
+}
+
+// Addr 返回代理的 host:port，未指定端口时使用协议的默认端口
+func (p *Proxy) Addr() string {
+	if len(p.URL.Port()) > 0 {
+		return p.URL.Host
+	}
+	port := "80"
+	switch strings.ToLower(p.URL.Scheme) {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(p.URL.Hostname(), port)
+}
+
+func (p *Proxy) healthy(now int64) bool {
+	return p.downUntil.Load() <= now
+}
+
+// Pool 按轮询或随机方式为每个请求选择代理，跳过不可用的代理
+type Pool struct {
+	proxies    []*Proxy
+	mode       string
+	retryAfter time.Duration
+	next       atomic.Uint64
+}
+
+// New 创建代理池，value 为逗号分隔的代理列表或每行一个代理的文件。
+// 未指定协议的代理按 http 处理，支持 http、https、socks5 和 socks5h
+func New(value, mode string, retryAfter time.Duration) (*Pool, error) {
+	list, err := parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return newPool(value, list, mode, retryAfter)
+}
+
+// NewList 与 New 相同，但 value 只作为逗号分隔的代理列表，不读取文件，
+// 用于 value 来自不受信任的输入（如 API 请求）时
+func NewList(value, mode string, retryAfter time.Duration) (*Pool, error) {
+	return newPool(value, splitList(value), mode, retryAfter)
+}
+
+func newPool(value string, list []string, mode string, retryAfter time.Duration) (*Pool, error) {
+	switch mode {
+	case "":
+		mode = ModeRoundRobin
+	case ModeRoundRobin, ModeRandom:
+	default:
+		return nil, fmt.Errorf("unsupported proxy mode %q", mode)
+	}
+	if retryAfter <= 0 {
+		retryAfter = DefaultRetryAfter
+	}
+
+	pool := &Pool{mode: mode, retryAfter: retryAfter}
+	for _, item := range list {
+		if !strings.Contains(item, "://") {
+			item = "http://" + item
+		}
+		u, err := url.Parse(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %w", item, err)
+		}
+		switch strings.ToLower(u.Scheme) {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %s", u.Scheme)
+		}
+		if len(u.Host) == 0 {
+			return nil, fmt.Errorf("invalid proxy %s", item)
+		}
+		pool.proxies = append(pool.proxies, &Proxy{URL: u})
+	}
+	if len(pool.proxies) == 0 {
+		return nil, fmt.Errorf("no proxy found in %s", value)
+	}
+
+	return pool, nil
+}
+
+// parse 读取代理列表，value 是已存在的文件时按行读取，忽略空行和 # 开头的注释
+func parse(value string) ([]string, error) {
+	if !fileutil.FileExists(value) {
+		return splitList(value), nil
+	}
+
+	f, err := os.Open(value)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	return list, s.Err()
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (pool *Pool) Len() int {
+	return len(pool.proxies)
+}
+
+// Proxies 返回池中的全部代理
+func (pool *Pool) Proxies() []*Proxy {
+	return pool.proxies
+}
+
+// Next 选择下一个可用的代理，全部不可用时返回最早恢复的代理，扫描不会因此停住
+func (pool *Pool) Next() *Proxy {
+	n := len(pool.proxies)
+	if n == 1 {
+		return pool.proxies[0]
+	}
+
+	var start int
+	if pool.mode == ModeRandom {
+		start = rand.IntN(n)
+	} else {
+		start = int((pool.next.Add(1) - 1) % uint64(n))
+	}
+
+	now := time.Now().UnixNano()
+	earliest := pool.proxies[start]
+	for i := 0; i < n; i++ {
+		p := pool.proxies[(start+i)%n]
+		if p.healthy(now) {
+			return p
+		}
+		if p.downUntil.Load() < earliest.downUntil.Load() {
+			earliest = p
+		}
+	}
+	return earliest
+}
+
+// MarkFailed 将代理标记为不可用，retryAfter 之后重新参与选择
+func (pool *Pool) MarkFailed(p *Proxy) {
+	if p == nil {
+		return
+	}
+	now := time.Now().UnixNano()
+	was := p.downUntil.Swap(now + int64(pool.retryAfter))
+	if was <= now {
+		gologger.Warning().Msgf("Proxy %s is unhealthy, retry in %s", p, pool.retryAfter)
+	}
+}
+
+// MarkOK 请求成功后恢复代理
+func (pool *Pool) MarkOK(p *Proxy) {
+	if p == nil {
+		return
+	}
+	p.downUntil.Store(0)
+}
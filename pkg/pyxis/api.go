@@ -1,17 +1,23 @@
 package pyxis
 
 import (
+	"context"
+	"sync"
+
 	"github.com/zan8in/pyxis/pkg/result"
 )
 
 type Scanner struct {
 	options *Options
 	Result  *result.Result
+
+	mu     sync.Mutex
+	runner *Runner
 }
 
 func NewScanner(options *Options) (*Scanner, error) {
 
-	if options.Host == nil && options.HostsFile == "" {
+	if options.Host == nil && options.HostsFile == "" && !options.Stdin {
 		return nil, errNoInputList
 	}
 
@@ -19,10 +25,35 @@ func NewScanner(options *Options) (*Scanner, error) {
 		options.Timeout = DefaultTimeout
 	}
 
+	if options.TargetTimeout <= 0 {
+		options.TargetTimeout = DefaultTargetTimeout
+	}
+
+	if options.FingerprintTimeout <= 0 {
+		options.FingerprintTimeout = DefaultFingerprintTimeout
+	}
+
+	if options.CdnTimeout <= 0 {
+		options.CdnTimeout = DefaultCdnTimeout
+	}
+
 	if options.RateLimit <= 0 {
 		options.autoChangeRateLimit()
 	}
 
+	if options.Adaptive {
+		if options.RateMin <= 0 {
+			options.RateMin = DefaultRateMin
+		}
+		if err := options.validateAdaptive(); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.StatsInterval <= 0 {
+		options.StatsInterval = DefaultStatsInterval
+	}
+
 	if options.Retries <= 0 {
 		options.Retries = DefaultRetries
 	}
@@ -36,12 +67,22 @@ func NewScanner(options *Options) (*Scanner, error) {
 }
 
 func (s *Scanner) Run() error {
+	return s.RunContext(context.Background())
+}
+
+// RunContext 扫描到 ctx 取消或全部完成为止，每个结果完成时调用 Options.OnResult，
+// 取消时 Result 中保留已完成的结果并返回 ctx 的错误。设置了 OnResult 时结果由回调保存，Result 为空
+func (s *Scanner) RunContext(ctx context.Context) error {
 	runner, err := NewRunner(s.options)
 	if err != nil {
 		return err
 	}
 
-	runner.ApiRun()
+	s.mu.Lock()
+	s.runner = runner
+	s.mu.Unlock()
+
+	err = runner.ApiRunContext(ctx)
 
 	if runner.Result.HasHostResult() {
 		for hostResult := range runner.Result.GetHostResult() {
@@ -49,5 +90,16 @@ func (s *Scanner) Run() error {
 		}
 	}
 
-	return nil
+	return err
+}
+
+// Stats 返回当前（或最近一次）扫描的进度
+func (s *Scanner) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.runner == nil {
+		return Stats{}
+	}
+	return s.runner.Stats()
 }
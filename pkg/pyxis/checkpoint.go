@@ -0,0 +1,236 @@
+package pyxis
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zan8in/gologger"
+	"github.com/zan8in/pyxis/pkg/util/fileutil"
+)
+
+const checkpointInterval = 5 * time.Second
+
+// checkpoint 记录断点续扫所需的状态：Position 之前的输入行已全部完成，
+// Done 为 Position 之后已完成的目标。方法均可在 nil 上调用，未启用 -resume 时不做任何事
+type checkpoint struct {
+	sync.Mutex
+	path    string
+	resumed bool
+
+	Input    string         `json:"input"`
+	Position int            `json:"position"`
+	Done     map[string]int `json:"done"`
+
+	start    int              // 本次启动时已完成的输入行数
+	done     map[string]int   // 已完成的目标及其所属输入行
+	lines    map[int]int      // 输入行尚未完成的目标数
+	pending  map[string][]int // 已投递未完成的目标及其所属输入行
+	finished map[int]struct{} // Position 之后已完成的输入行
+}
+
+// checkpointInput 描述本次扫描的输入，输入变化时状态文件不再适用
+func checkpointInput(options *Options) string {
+	return strings.Join([]string{
+		strings.Join(options.Host, ","),
+		options.HostsFile,
+		options.InputFormat,
+		options.Ports,
+	}, "|")
+}
+
+// loadCheckpoint 读取状态文件，不存在时从头开始扫描
+func loadCheckpoint(options *Options) (*checkpoint, error) {
+	if len(options.Resume) == 0 {
+		return nil, nil
+	}
+
+	cp := &checkpoint{
+		path:     options.Resume,
+		Input:    checkpointInput(options),
+		done:     make(map[string]int),
+		lines:    make(map[int]int),
+		pending:  make(map[string][]int),
+		finished: make(map[int]struct{}),
+	}
+
+	if !fileutil.FileExists(options.Resume) {
+		return cp, nil
+	}
+
+	data, err := os.ReadFile(options.Resume)
+	if err != nil {
+		return nil, err
+	}
+
+	var saved checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+
+	if saved.Input != cp.Input {
+		gologger.Warning().Msgf("Resume file %s was created for another input, starting over\n", options.Resume)
+		return cp, nil
+	}
+
+	cp.resumed = true
+	cp.start = saved.Position
+	cp.Position = saved.Position
+	for target, line := range saved.Done {
+		cp.done[target] = line
+	}
+	gologger.Info().Msgf("Resuming from input position %d, %d targets already done\n", saved.Position, len(saved.Done))
+
+	return cp, nil
+}
+
+// skip 判断输入行在上次扫描中是否已全部完成
+func (cp *checkpoint) skip(line int) bool {
+	return cp != nil && line < cp.start
+}
+
+// begin 在展开输入行之前调用，防止展开过程中该行被提前判定为完成
+func (cp *checkpoint) begin(line int) {
+	if cp == nil {
+		return
+	}
+	cp.Lock()
+	defer cp.Unlock()
+
+	cp.lines[line]++
+}
+
+// end 在输入行展开结束后调用
+func (cp *checkpoint) end(line int) {
+	if cp == nil {
+		return
+	}
+	cp.Lock()
+	defer cp.Unlock()
+
+	cp.release(line)
+}
+
+// add 登记输入行展开出的目标，目标已完成时返回 false
+func (cp *checkpoint) add(line int, target string) bool {
+	if cp == nil {
+		return true
+	}
+	cp.Lock()
+	defer cp.Unlock()
+
+	if _, ok := cp.done[target]; ok {
+		return false
+	}
+	cp.lines[line]++
+	cp.pending[target] = append(cp.pending[target], line)
+	return true
+}
+
+// finish 在目标扫描结束（成功或失败）后调用
+func (cp *checkpoint) finish(target string) {
+	if cp == nil {
+		return
+	}
+	cp.Lock()
+	defer cp.Unlock()
+
+	lines, ok := cp.pending[target]
+	if !ok {
+		// 扫描过程中追加的目标不属于任何输入行
+		return
+	}
+
+	line := lines[0]
+	if len(lines) == 1 {
+		delete(cp.pending, target)
+	} else {
+		cp.pending[target] = lines[1:]
+	}
+
+	cp.done[target] = line
+	cp.release(line)
+}
+
+func (cp *checkpoint) release(line int) {
+	cp.lines[line]--
+	if cp.lines[line] > 0 {
+		return
+	}
+
+	delete(cp.lines, line)
+	cp.finished[line] = struct{}{}
+	for {
+		if _, ok := cp.finished[cp.Position]; !ok {
+			break
+		}
+		delete(cp.finished, cp.Position)
+		cp.Position++
+	}
+}
+
+// save 原子地写入状态文件，只保留 Position 之后的已完成目标
+func (cp *checkpoint) save() error {
+	if cp == nil {
+		return nil
+	}
+	cp.Lock()
+	for target, line := range cp.done {
+		if line < cp.Position {
+			delete(cp.done, target)
+		}
+	}
+	cp.Done = cp.done
+	data, err := json.Marshal(cp)
+	cp.Unlock()
+	if err != nil {
+		return err
+	}
+
+	temp := cp.path + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, cp.path)
+}
+
+// autoSave 定期保存状态，直到 stop 被关闭
+func (cp *checkpoint) autoSave(stop <-chan struct{}) {
+	if cp == nil {
+		return
+	}
+
+	ticker := time.NewTicker(checkpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := cp.save(); err != nil {
+				gologger.Warning().Msgf("Could not save resume file %s: %s\n", cp.path, err)
+			}
+		}
+	}
+}
+
+// close 扫描完整结束时删除状态文件，被中断时保存最终状态
+func (cp *checkpoint) close(interrupted bool) {
+	if cp == nil {
+		return
+	}
+
+	if !interrupted {
+		os.Remove(cp.path)
+		return
+	}
+
+	if err := cp.save(); err != nil {
+		gologger.Error().Msgf("Could not save resume file %s: %s\n", cp.path, err)
+		return
+	}
+	gologger.Info().Msgf("Scan state saved, run again with -resume %s to continue\n", cp.path)
+}
@@ -0,0 +1,155 @@
+package pyxis
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/zan8in/gologger"
+	"github.com/zan8in/pyxis/pkg/result"
+	"github.com/zan8in/pyxis/pkg/util/fileutil"
+	"github.com/zan8in/pyxis/pkg/util/hashutil"
+)
+
+// Cluster 是一组状态码相同、响应体相同或 simhash 相近的页面
+type Cluster struct {
+	ID         int      `json:"id"`
+	Size       int      `json:"size"`
+	StatusCode int      `json:"statuscode"`
+	Title      string   `json:"title,omitempty"`
+	BodySHA256 string   `json:"bodysha256"` // 第一个成员的响应体哈希
+	Simhash    string   `json:"simhash"`
+	Members    []string `json:"members"`
+}
+
+// clusterer 在 Listener 中收集成功的结果，不保留响应内容，但每个成员都保留 URL，
+// 内存随成功结果的数量增长，-stream 时同样如此
+type clusterer struct {
+	distance int
+
+	clusters []*Cluster
+	simhash  []uint64       // 与 clusters 对应的代表 simhash
+	exact    map[string]int // 响应体哈希到 clusters 下标，完全相同的页面不必比较 simhash
+	seen     map[string]struct{}
+}
+
+func newClusterer(options *Options) *clusterer {
+	if len(options.Cluster) == 0 {
+		return nil
+	}
+	return &clusterer{
+		distance: options.ClusterDistance,
+		exact:    make(map[string]int),
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// Add 将结果归入第一个状态码相同且 simhash 距离不超过 -cluster-distance 的簇，没有则新建。
+// 空页面和没有文字的页面 simhash 为 0，只按响应体哈希归类
+func (c *clusterer) Add(rst *result.HostResult) {
+	if c == nil || rst.Flag != 0 {
+		return
+	}
+	if _, ok := c.seen[rst.Key()]; ok {
+		return
+	}
+	c.seen[rst.Key()] = struct{}{}
+
+	key := fmt.Sprintf("%d:%s", rst.StatusCode, rst.BodySHA256)
+	if i, ok := c.exact[key]; ok {
+		c.join(i, rst)
+		return
+	}
+
+	simhash, _ := hashutil.ParseSimhash(rst.Simhash)
+	for i, cluster := range c.clusters {
+		if simhash == 0 || c.simhash[i] == 0 {
+			continue
+		}
+		if cluster.StatusCode == rst.StatusCode && hashutil.Distance(c.simhash[i], simhash) <= c.distance {
+			c.exact[key] = i
+			c.join(i, rst)
+			return
+		}
+	}
+
+	c.exact[key] = len(c.clusters)
+	c.clusters = append(c.clusters, &Cluster{
+		StatusCode: rst.StatusCode,
+		Title:      rst.Title,
+		BodySHA256: rst.BodySHA256,
+		Simhash:    rst.Simhash,
+	})
+	c.simhash = append(c.simhash, simhash)
+	c.join(len(c.clusters)-1, rst)
+}
+
+func (c *clusterer) join(i int, rst *result.HostResult) {
+	c.clusters[i].Size++
+	c.clusters[i].Members = append(c.clusters[i].Members, rst.FullUrl)
+}
+
+// Clusters 返回按大小降序排列的簇
+func (c *clusterer) Clusters() []*Cluster {
+	clusters := make([]*Cluster, len(c.clusters))
+	copy(clusters, c.clusters)
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].Size > clusters[j].Size
+	})
+	for i, cluster := range clusters {
+		cluster.ID = i + 1
+	}
+	return clusters
+}
+
+// writeClusters 将聚类结果写入 -cluster 文件，.json 为 JSON 数组，其他为文本格式
+func (r *Runner) writeClusters() {
+	if r.cluster == nil {
+		return
+	}
+
+	clusters := r.cluster.Clusters()
+	output := r.Options.Cluster
+
+	if folder := filepath.Dir(output); !fileutil.FolderExists(folder) {
+		if err := os.MkdirAll(folder, 0700); err != nil {
+			gologger.Error().Msgf("Could not create output folder %s: %s\n", folder, err)
+			return
+		}
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		gologger.Error().Msgf("Could not create file %s: %s\n", output, err)
+		return
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if fileutil.FileExt(output) == fileutil.FILE_JSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(clusters)
+	} else {
+		for _, cluster := range clusters {
+			fmt.Fprintf(w, "# cluster %d\tsize %d\tstatus %d\tsimhash %s\t%s\n",
+				cluster.ID, cluster.Size, cluster.StatusCode, cluster.Simhash, cluster.Title)
+			for _, member := range cluster.Members {
+				fmt.Fprintln(w, member)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		gologger.Error().Msgf("Could not write clusters %s: %s\n", output, err)
+		return
+	}
+
+	gologger.Info().Msgf("Grouped results into %d clusters: %s", len(clusters), output)
+}
@@ -0,0 +1,98 @@
+package pyxis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zan8in/pyxis/pkg/result"
+	"github.com/zan8in/pyxis/pkg/util/hashutil"
+)
+
+func testPage(url string, status int, body string) *result.HostResult {
+	return &result.HostResult{
+		FullUrl:    url,
+		StatusCode: status,
+		BodySHA256: hashutil.SHA256([]byte(body)),
+		Simhash:    hashutil.FormatSimhash(hashutil.Simhash(body)),
+	}
+}
+
+// testTemplate 生成几百个词的模板页面，每个 topic 一段
+func testTemplate(title string, topics ...string) string {
+	var b strings.Builder
+	b.WriteString(title + ". ")
+	for _, topic := range topics {
+		fmt.Fprintf(&b, "Related searches for %s: compare %s offers, cheap %s deals and the best %s reviews of the year. ", topic, topic, topic, topic)
+	}
+	return b.String()
+}
+
+func TestClustererAdd(t *testing.T) {
+	topics := []string{
+		"hosting", "email", "domains", "insurance", "loans", "travel", "flights", "hotels",
+		"cars", "phones", "laptops", "furniture", "gardening", "recipes", "fitness", "music",
+		"movies", "books", "courses", "jobs", "dating", "pets", "shoes", "watches",
+	}
+	parked := testTemplate("This domain is parked free of charge", topics...)
+	other := testTemplate("Welcome to our online store", "electronics", "clothing", "groceries", "toys", "jewelry", "tools")
+
+	c := newClusterer(&Options{Cluster: "clusters.txt", ClusterDistance: DefaultClusterDistance})
+	c.Add(testPage("http://a.test", 200, parked+" session 1a2b3c"))
+	c.Add(testPage("http://b.test", 200, parked+" session 9f8e7d"))
+	c.Add(testPage("http://c.test", 200, parked+" Powered by openresty."))
+	c.Add(testPage("http://d.test", 200, other))
+	c.Add(testPage("http://e.test", 404, parked))
+	c.Add(testPage("http://a.test", 200, parked))                // 重复的 URL
+	c.Add(&result.HostResult{FullUrl: "http://f.test", Flag: 1}) // 失败的结果
+
+	clusters := c.Clusters()
+	if len(clusters) != 3 {
+		t.Fatalf("got %d clusters, want 3", len(clusters))
+	}
+
+	first := clusters[0]
+	if first.ID != 1 || first.StatusCode != 200 || first.Size != 3 {
+		t.Fatalf("first cluster = %+v, want the 3 parked pages with status 200", first)
+	}
+	for i, want := range []string{"http://a.test", "http://b.test", "http://c.test"} {
+		if first.Members[i] != want {
+			t.Errorf("member %d = %s, want %s", i, first.Members[i], want)
+		}
+	}
+
+	// 状态码不同的相同页面单独成簇
+	for _, cluster := range clusters[1:] {
+		if cluster.Size != 1 {
+			t.Errorf("cluster %v should only hold one page", cluster.Members)
+		}
+		if cluster.Members[0] == "http://e.test" && cluster.StatusCode != 404 {
+			t.Errorf("404 page joined a cluster with status %d", cluster.StatusCode)
+		}
+	}
+}
+
+// 没有文字的不同页面 simhash 都为 0，不能归为一簇
+func TestClustererEmptySimhash(t *testing.T) {
+	c := newClusterer(&Options{Cluster: "clusters.txt", ClusterDistance: DefaultClusterDistance})
+	c.Add(testPage("http://a.test", 200, ""))
+	c.Add(testPage("http://b.test", 200, "<html><body><img src=logo.png></body></html>"))
+	c.Add(testPage("http://c.test", 200, "12345"))
+	c.Add(testPage("http://d.test", 200, ""))
+
+	clusters := c.Clusters()
+	if len(clusters) != 3 {
+		t.Fatalf("got %d clusters, want 3", len(clusters))
+	}
+	if clusters[0].Size != 2 || clusters[0].Members[1] != "http://d.test" {
+		t.Errorf("identical empty pages should share a cluster: %v", clusters[0].Members)
+	}
+}
+
+func TestClustererNil(t *testing.T) {
+	c := newClusterer(&Options{})
+	if c != nil {
+		t.Fatal("clusterer should be nil without -cluster")
+	}
+	c.Add(testPage("http://a.test", 200, "x")) // 未开启时 Add 不做任何事
+}
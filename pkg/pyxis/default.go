@@ -4,6 +4,17 @@ const (
 	DefaultRetries   = 1
 	DefaultTimeout   = 10
 	DefaultRateLimit = 10 // 从150降低到50，更保守的默认值
+	DefaultRateMin   = 1
+	DefaultRateScale = 4 // 未指定 -rate-max 时自适应速率的上限为 -rate 的倍数
+
+	DefaultStatsInterval = 5
+	DefaultProxyRetry    = 30
+
+	DefaultClusterDistance = 3 // 64 位 simhash 中最多相差的位数
+
+	DefaultTargetTimeout      = 30 // 单个 target 的整体超时
+	DefaultFingerprintTimeout = 5  // 指纹识别超时
+	DefaultCdnTimeout         = 3  // CDN 检测超时
 
 	HostTempFile = "pyxis-host-temp-*"
 
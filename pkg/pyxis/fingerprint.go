@@ -0,0 +1,65 @@
+package pyxis
+
+import (
+	"strings"
+
+	"github.com/zan8in/gologger"
+	"github.com/zan8in/pyxis/pkg/fingerprint"
+	"github.com/zan8in/pyxis/pkg/result"
+)
+
+const (
+	libraRuleID = "libra"
+	tlsfpRuleID = "tls-fp-db"
+)
+
+// loadFingerprintRules 加载 -fingerprint-file 指定的自定义指纹规则
+func loadFingerprintRules(path string) ([]*fingerprint.Rule, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	rules, err := fingerprint.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	gologger.Info().Msgf("Loaded %d custom fingerprint rules", len(rules))
+	return rules, nil
+}
+
+// matchFingerprintRules 使用自定义规则识别指纹，需在丢弃响应内容之前调用
+func (r *Runner) matchFingerprintRules(rst *result.HostResult) {
+	for _, fp := range fingerprint.Match(r.rules, rst) {
+		addFingerprint(rst, fp)
+	}
+}
+
+// libraFingerprints 将 libra 识别出的指纹名称转换为结构化指纹，libra 不提供规则详情
+func libraFingerprints(rst *result.HostResult) {
+	for _, name := range strings.Split(rst.FingerPrint, ",") {
+		if len(name) == 0 {
+			continue
+		}
+		rst.Fingerprints = append(rst.Fingerprints, result.Fingerprint{
+			Name:   name,
+			RuleID: libraRuleID,
+		})
+	}
+}
+
+// addFingerprint 将指纹合并到 Fingerprints 和 FingerPrint，同名指纹已存在时只补充缺失的版本
+func addFingerprint(rst *result.HostResult, fp result.Fingerprint) {
+	for i := range rst.Fingerprints {
+		if strings.EqualFold(rst.Fingerprints[i].Name, fp.Name) {
+			if len(rst.Fingerprints[i].Version) == 0 {
+				rst.Fingerprints[i].Version = fp.Version
+			}
+			return
+		}
+	}
+
+	rst.Fingerprints = append(rst.Fingerprints, fp)
+	if !strings.Contains(","+rst.FingerPrint+",", ","+fp.Name+",") {
+		rst.FingerPrint = fingerprintSlice2String([]string{rst.FingerPrint, fp.Name})
+	}
+}
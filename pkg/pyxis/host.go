@@ -1,14 +1,18 @@
 package pyxis
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/remeh/sizedwaitgroup"
 	"github.com/zan8in/gologger"
+	"github.com/zan8in/pyxis/pkg/importer"
+	"github.com/zan8in/pyxis/pkg/result"
 	"github.com/zan8in/pyxis/pkg/util/iputil"
 )
 
@@ -17,6 +21,14 @@ func (r *Runner) PreprocessHost() error {
 		err error
 	)
 
+	// 输入读取和扫描中的目标都可能追加新目标，全部完成后才关闭队列
+	r.inflight.Add(1)
+	defer r.inflight.Done()
+	go func() {
+		r.inflight.Wait()
+		close(r.hostChan)
+	}()
+
 	hostTemp, err := os.CreateTemp("", HostTempFile)
 	if err != nil {
 		return err
@@ -37,7 +49,8 @@ func (r *Runner) PreprocessHost() error {
 		}
 	}
 
-	if len(r.Options.HostsFile) > 0 {
+	// 非文本格式的目标文件在下面按格式流式解析，不写入临时文件
+	if len(r.Options.HostsFile) > 0 && !r.hasInputFormat() {
 		f, err := os.Open(r.Options.HostsFile)
 		if err != nil {
 			return err
@@ -57,25 +70,62 @@ func (r *Runner) PreprocessHost() error {
 	}
 	defer f.Close()
 
-	defer close(r.hostChan)
-
 	wg := sizedwaitgroup.New(r.Options.RateLimit)
-	s := bufio.NewScanner(f)
-	for s.Scan() {
+	err = r.processReader(f, importer.FormatTXT, &wg)
+
+	if len(r.Options.HostsFile) > 0 && r.hasInputFormat() && err == nil {
+		err = r.processFile(r.Options.HostsFile, &wg)
+	}
+
+	// 标准输入直接流式投递，上游工具仍在输出时即可开始扫描
+	if r.Options.Stdin && err == nil {
+		err = r.processReader(os.Stdin, r.Options.InputFormat, &wg)
+	}
+	wg.Wait()
+
+	return err
+}
+
+func (r *Runner) processFile(fileName string, wg *sizedwaitgroup.SizedWaitGroup) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.processReader(f, r.Options.InputFormat, wg)
+}
+
+// processReader 按输入格式解析目标并并发投递
+func (r *Runner) processReader(reader io.Reader, format string, wg *sizedwaitgroup.SizedWaitGroup) error {
+	return importer.Parse(format, reader, func(target string) {
+		line := r.position
+		r.position++
+
+		// 停止后不再投递新目标，断点续扫时跳过已全部完成的输入
+		if r.stopCtx.Err() != nil || r.checkpoint.skip(line) {
+			return
+		}
+
+		r.checkpoint.begin(line)
 		wg.Add()
 		go func(target string) {
 			defer wg.Done()
-			if err := r.processTarget(target); err != nil {
+			defer r.checkpoint.end(line)
+			if err := r.processTarget(line, target); err != nil {
 				gologger.Warning().Msgf("%s\n", err)
 			}
-		}(s.Text())
-	}
-	wg.Wait()
+		}(target)
+	})
+}
 
-	return err
+// hasInputFormat 是否指定了 nmap/masscan/naabu 等非文本输入格式
+func (r *Runner) hasInputFormat() bool {
+	format := strings.ToLower(r.Options.InputFormat)
+	return len(format) > 0 && format != importer.FormatTXT
 }
 
-func (r *Runner) processTarget(target string) error {
+func (r *Runner) processTarget(line int, target string) error {
 	var err error
 
 	target = strings.TrimSpace(target)
@@ -83,11 +133,147 @@ func (r *Runner) processTarget(target string) error {
 		return nil
 	}
 
-	if iputil.IsCIDR(target) {
-		return nil
+	sendHost := func(host string) bool {
+		return r.sendHost(line, host)
+	}
+
+	// CIDR 和 IP 段按需逐个展开，不在内存中生成完整列表
+	switch {
+	case iputil.IsCIDR(target):
+		return iputil.WalkCIDR(target, sendHost)
+	case iputil.IsIPRange(target):
+		return iputil.WalkIPRange(target, sendHost)
+	case iputil.IsCidrWithExpansion(target):
+		return iputil.WalkCIDR(strings.ReplaceAll(target, "-", "/"), sendHost)
 	}
 
-	r.hostChan <- target
+	sendHost(target)
 	// gologger.Info().Msg(target)
 	return err
 }
+
+// sendHost 将单个目标投递到扫描队列，指定了端口列表时裸主机/IP 会按端口展开为 host:port
+func (r *Runner) sendHost(line int, host string) bool {
+	if len(r.ports) == 0 || !isBareHost(host) {
+		// IPv6 地址加上方括号，否则无法与端口区分
+		if iputil.IsIPv6(host) {
+			host = "[" + host + "]"
+		}
+		return r.dispatch(line, host)
+	}
+
+	for _, port := range r.ports {
+		if !r.dispatch(line, net.JoinHostPort(host, strconv.Itoa(port))) {
+			return false
+		}
+	}
+	return true
+}
+
+// dispatch 投递目标，扫描已停止时返回 false
+func (r *Runner) dispatch(line int, host string) bool {
+	// 断点续扫时已完成的目标不再投递
+	if !r.checkpoint.add(line, host) {
+		return true
+	}
+
+	// 输入中已有的 HTTPS 目标不再作为 SAN 追加
+	if r.Options.TLSSan {
+		if key := httpsKey(host); len(key) > 0 {
+			r.enqueued.Store(key, struct{}{})
+		}
+	}
+
+	r.inflight.Add(1)
+	select {
+	case r.hostChan <- host:
+		r.queued.Add(1)
+		return true
+	case <-r.stopCtx.Done():
+		r.inflight.Done()
+		return false
+	}
+}
+
+// enqueueHost 在扫描过程中追加 HTTPS 目标，异步投递避免与占满并发的扫描协程互相等待
+func (r *Runner) enqueueHost(host string) {
+	key := httpsKey(host)
+	if len(key) == 0 {
+		return
+	}
+	if _, loaded := r.enqueued.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	r.inflight.Add(1)
+	go func() {
+		select {
+		case r.hostChan <- host:
+			r.queued.Add(1)
+		case <-r.stopCtx.Done():
+			r.inflight.Done()
+		}
+	}()
+}
+
+// feedSANs 将证书中的 SAN 作为新目标加入扫描队列，用于发现隐藏的虚拟主机。
+// 证书来自跳转后的最终响应，跳转到其他主机时属于第三方，不加入
+func (r *Runner) feedSANs(rst *result.HostResult) {
+	if !r.Options.TLSSan || rst.TLSInfo == nil {
+		return
+	}
+
+	final, err := url.Parse(rst.FinalUrl)
+	if err != nil || !strings.EqualFold(final.Hostname(), strings.Trim(rst.Host, "[]")) {
+		return
+	}
+	// http 跳转到同一主机的 https 时，证书在最终的端口上
+	port := final.Port()
+
+	for _, san := range rst.TLSInfo.SANs {
+		san = strings.ToLower(strings.TrimSpace(san))
+		// 通配符证书无法确定具体主机名
+		if len(san) == 0 || strings.HasPrefix(san, "*") || san == rst.Host {
+			continue
+		}
+		if len(port) == 0 || port == "443" {
+			r.enqueueHost(HTTPS_PREFIX + san)
+		} else {
+			r.enqueueHost(HTTPS_PREFIX + net.JoinHostPort(san, port))
+		}
+	}
+}
+
+// httpsKey 返回目标会以 HTTPS 访问的地址 https://host:port，用于输入目标和 SAN 之间去重。
+// 裸主机和 host:port（80 端口除外）先以 HTTPS 访问；http:// 和带路径的目标返回空
+func httpsKey(host string) string {
+	explicit := strings.HasPrefix(host, HTTPS_PREFIX)
+	if !explicit {
+		if strings.HasPrefix(host, HTTP_PREFIX) {
+			return ""
+		}
+		host = HTTPS_PREFIX + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil || len(u.Hostname()) == 0 || (len(u.Path) > 0 && u.Path != "/") {
+		return ""
+	}
+
+	port := u.Port()
+	switch {
+	case len(port) == 0:
+		port = "443"
+	case port == "80" && !explicit:
+		// host:80 只以 HTTP 访问
+		return ""
+	}
+	return HTTPS_PREFIX + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+// isBareHost 判断目标是否为不带协议、端口和路径的主机名或 IP
+func isBareHost(host string) bool {
+	if iputil.IsIP(host) {
+		return true
+	}
+	return !strings.ContainsAny(host, ":/")
+}
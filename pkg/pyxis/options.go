@@ -7,23 +7,82 @@ import (
 	"github.com/pkg/errors"
 	"github.com/zan8in/goflags"
 	"github.com/zan8in/gologger"
+	"github.com/zan8in/pyxis/pkg/http/retryhttpclient"
+	"github.com/zan8in/pyxis/pkg/importer"
+	"github.com/zan8in/pyxis/pkg/proxypool"
+	"github.com/zan8in/pyxis/pkg/result"
+	"github.com/zan8in/pyxis/pkg/util/fileutil"
 )
 
 type Options struct {
 	Host      goflags.StringSlice // Host is the single host or comma-separated list of hosts to find ports for
 	HostsFile string              // HostsFile is the file containing list of hosts to find port for
+	Ports     string              // Ports is the list of ports to probe on every bare host
+	Stdin     bool                // Stdin reads targets from standard input
 
-	Retries   int    // Retries is the number of retries for the port
-	RateLimit int    // RateLimit is the rate of port scan requests
-	Timeout   int    // Timeout is the seconds to wait for ports to respond
-	Proxy     string // http/socks5 proxy to use
-	Output    string // Output is the file to write found ports to.
+	InputFormat string              // InputFormat is the format of the target file or stdin (txt, nmap, masscan, naabu)
+	Paths       goflags.StringSlice // Paths is the list of paths to request on every live host
+	PathFile    string              // PathFile is the file containing list of paths to request
+
+	Retries    int    // Retries is the number of retries for the port
+	RateLimit  int    // RateLimit is the rate of port scan requests
+	Timeout    int    // Timeout is the seconds to wait for ports to respond
+	Proxy      string // http/socks5 proxy to use
+	ProxyMode  string // ProxyMode is how proxies are picked from the pool (round-robin, random)
+	ProxyRetry int    // ProxyRetry is the seconds before a failing proxy is tried again
+
+	ProxyPool *proxypool.Pool // ProxyPool is used instead of Proxy when set, e.g. a list built from untrusted input
+
+	RateMin  int  // RateMin is the lower bound of the adaptive rate
+	RateMax  int  // RateMax is the upper bound of the adaptive rate
+	Adaptive bool // Adaptive adjusts the rate to the observed errors and latency
+
+	MaxHostConns int // MaxHostConns is the number of concurrent requests per host, 0 is unlimited
+	MaxIPConns   int // MaxIPConns is the number of concurrent requests per ip, 0 is unlimited
+
+	Headers   goflags.StringSlice // Headers are the "Name: value" headers sent with every request
+	Cookie    string              // Cookie is sent with every request
+	Method    string              // Method is the method of the target and path probes
+	Body      string              // Body is the body of the target and path probes
+	UserAgent string              // UserAgent replaces the random user agent
+
+	TargetTimeout      int // TargetTimeout is the seconds budget for scanning a single target
+	FingerprintTimeout int // FingerprintTimeout is the seconds to wait for fingerprint matching
+	CdnTimeout         int // CdnTimeout is the seconds to wait for the cdn check
+
+	FollowRedirect string // FollowRedirect is the redirect policy (all, same-host, none)
+	Output         string // Output is the file to write found ports to.
+	Resume         string // Resume is the state file used to continue an interrupted scan
 
 	Silent bool // Silent is the flag to show only results
+
+	Stats         bool // Stats prints the scan progress and current rate periodically
+	StatsInterval int  // StatsInterval is the seconds between two stats lines
+	JSON          bool // JSON prints results as json lines to stdout
+	Stream        bool // Stream writes results to the output as they arrive and keeps memory bounded
+
+	Cluster         string // Cluster is the file to write groups of near-identical pages to
+	ClusterDistance int    // ClusterDistance is the max simhash distance between pages of a cluster
+
 	Cdn    bool
 	Clear  bool // Clear is the flag to show only successful results
+	TLSSan bool // TLSSan feeds subject alternative names of certificates back into the scan
+
+	Vhost     bool   // Vhost enumerates virtual hosts of ip targets with certificate SANs and PTR names
+	VhostFile string // VhostFile is the file of candidate virtual host names, implies Vhost
+
+	Wildcard     bool // Wildcard drops results that match the response of a random subdomain of the parent domain
+	KeepWildcard bool // KeepWildcard keeps wildcard results and marks them instead, implies Wildcard
+
+	TLSFingerprint   bool   // TLSFingerprint computes the tls server fingerprint of https hosts
+	TLSFingerprintDB string // TLSFingerprintDB is the file mapping tls server fingerprints to names
+	FingerprintFile  string // FingerprintFile is the yaml/json file or directory of custom fingerprint rules
 
 	Version bool
+
+	// OnResult is called for every finished result when pyxis is used as a library
+	OnResult func(*result.HostResult)
+	NoStdout bool // NoStdout disables printing results to stdout
 }
 
 func ParseOptions() *Options {
@@ -35,7 +94,14 @@ func ParseOptions() *Options {
 
 	flagSet.CreateGroup("input", "Input",
 		flagSet.StringSliceVarP(&options.Host, "t", "target", nil, "hosts to scan ports for (comma-separated)", goflags.NormalizedStringSliceOptions),
-		flagSet.StringVarP(&options.HostsFile, "T", "target-file", "", "list of hosts to scan ports (file)"),
+		flagSet.StringVarP(&options.HostsFile, "T", "target-file", "", "list of hosts to scan ports (file, - for stdin)"),
+		flagSet.StringVarP(&options.InputFormat, "if", "input-format", "txt", "format of the target file/stdin (txt,nmap,masscan,naabu)"),
+		flagSet.StringVarP(&options.Ports, "p", "ports", "", "ports to probe on every host (e.g. 80,443,8000-8100,top-web-100)"),
+	)
+
+	flagSet.CreateGroup("path", "Path",
+		flagSet.StringSliceVar(&options.Paths, "path", nil, "paths to request on every live host (comma-separated)", goflags.CommaSeparatedStringSliceOptions),
+		flagSet.StringVar(&options.PathFile, "path-file", "", "list of paths to request on every live host (file)"),
 	)
 
 	flagSet.CreateGroup("version", "Version",
@@ -43,23 +109,57 @@ func ParseOptions() *Options {
 	)
 
 	flagSet.CreateGroup("output", "Output",
-		flagSet.StringVarP(&options.Output, "output", "o", "", "file to write output to (optional), support format: txt,csv,json"),
+		flagSet.StringVarP(&options.Output, "output", "o", "", "file to write output to (optional), support format: txt,csv,json,jsonl"),
+		flagSet.BoolVar(&options.JSON, "json", false, "write output in json lines format to stdout"),
+		flagSet.StringVar(&options.Resume, "resume", "", "state file to save progress to and resume an interrupted scan from"),
+		flagSet.BoolVar(&options.Stream, "stream", false, "stream results to the output file and drop response bodies to keep memory bounded"),
+		flagSet.StringVar(&options.Cluster, "cluster", "", "file to write clusters of near-identical pages to (txt,json)"),
+		flagSet.IntVar(&options.ClusterDistance, "cluster-distance", DefaultClusterDistance, "max simhash distance (bits) between pages of a cluster"),
 	)
 
 	flagSet.CreateGroup("optimization", "Optimization",
 		flagSet.IntVar(&options.Retries, "retries", DefaultRetries, "number of retries for the port scan"),
 		flagSet.IntVar(&options.Timeout, "timeout", DefaultTimeout, "seconds to wait before timing out"),
+		flagSet.IntVar(&options.TargetTimeout, "target-timeout", DefaultTargetTimeout, "seconds budget for scanning a single target"),
+		flagSet.IntVar(&options.FingerprintTimeout, "fingerprint-timeout", DefaultFingerprintTimeout, "seconds to wait for fingerprint matching"),
+		flagSet.IntVar(&options.CdnTimeout, "cdn-timeout", DefaultCdnTimeout, "seconds to wait for the cdn check"),
+		flagSet.StringVarP(&options.FollowRedirect, "fr", "follow-redirect", retryhttpclient.RedirectAll, "redirect policy (all,same-host,none)"),
 		flagSet.BoolVar(&options.Cdn, "cdn", false, "check if the host is a cdn"),
+		flagSet.BoolVar(&options.TLSSan, "tls-san", false, "scan subject alternative names found in tls certificates"),
+		flagSet.BoolVar(&options.Vhost, "vhost", false, "enumerate virtual hosts of ip targets with tls sans and ptr names"),
+		flagSet.StringVar(&options.VhostFile, "vhost-file", "", "file of candidate virtual host names (implies -vhost)"),
+		flagSet.BoolVar(&options.Wildcard, "wildcard", false, "drop results matching the response of a random subdomain of the parent domain"),
+		flagSet.BoolVar(&options.KeepWildcard, "keep-wildcard", false, "keep wildcard results and mark them (implies -wildcard)"),
+		flagSet.BoolVar(&options.TLSFingerprint, "tls-fp", false, "compute the tls server fingerprint of https hosts"),
+		flagSet.StringVar(&options.TLSFingerprintDB, "tls-fp-db", "", "file of known tls server fingerprints (fingerprint name per line)"),
+		flagSet.StringVarP(&options.FingerprintFile, "ff", "fingerprint-file", "", "yaml/json file or directory of custom fingerprint rules"),
 		flagSet.BoolVar(&options.Silent, "silent", false, "only results only"),
 		flagSet.BoolVar(&options.Clear, "clear", false, "only show successful results"),
 	)
 
+	flagSet.CreateGroup("request", "Request",
+		flagSet.StringSliceVarP(&options.Headers, "header", "H", nil, "custom header sent with every request, supports {{host}} placeholders (Name: value)", goflags.StringSliceOptions),
+		flagSet.StringVar(&options.Cookie, "cookie", "", "cookie sent with every request"),
+		flagSet.StringVar(&options.Method, "method", "GET", "method of the target and path probes"),
+		flagSet.StringVar(&options.Body, "body", "", "body of the target and path probes"),
+		flagSet.StringVar(&options.UserAgent, "ua", "", "user agent sent with every request (default random)"),
+	)
+
 	flagSet.CreateGroup("rate-limit", "Rate-limit",
 		flagSet.IntVar(&options.RateLimit, "rate", DefaultRateLimit, "packets to send per second"),
+		flagSet.BoolVar(&options.Adaptive, "adaptive", false, "adjust the rate to the observed errors and latency, starting at -rate"),
+		flagSet.IntVar(&options.RateMin, "rate-min", DefaultRateMin, "minimum rate of the adaptive rate"),
+		flagSet.IntVar(&options.RateMax, "rate-max", 0, "maximum rate of the adaptive rate (default 4 times -rate)"),
+		flagSet.IntVar(&options.MaxHostConns, "max-host-conns", 0, "concurrent requests per host (0 is unlimited)"),
+		flagSet.IntVar(&options.MaxIPConns, "max-ip-conns", 0, "concurrent requests per ip, shared by all vhosts on it, ignored with -proxy (0 is unlimited)"),
+		flagSet.BoolVar(&options.Stats, "stats", false, "print scan progress and current rate"),
+		flagSet.IntVar(&options.StatsInterval, "stats-interval", DefaultStatsInterval, "seconds between two stats lines"),
 	)
 
 	flagSet.CreateGroup("proxy", "Proxy",
 		flagSet.StringVar(&options.Proxy, "proxy", "", "list of http/socks5 proxy to use (comma separated or file input)"),
+		flagSet.StringVar(&options.ProxyMode, "proxy-mode", proxypool.ModeRoundRobin, "how to pick a proxy for each request (round-robin,random)"),
+		flagSet.IntVar(&options.ProxyRetry, "proxy-retry", DefaultProxyRetry, "seconds before a failing proxy is tried again"),
 	)
 
 	_ = flagSet.Parse()
@@ -79,6 +179,9 @@ func ParseOptions() *Options {
 var (
 	errNoInputList = errors.New("no input list provided")
 	errZeroValue   = errors.New("cannot be zero")
+
+	errUnsupportedFormat   = errors.New("unsupported input format")
+	errUnsupportedRedirect = errors.New("unsupported redirect policy")
 )
 
 func (options *Options) validateOptions() (err error) {
@@ -87,23 +190,95 @@ func (options *Options) validateOptions() (err error) {
 		os.Exit(0)
 	}
 
-	if options.Host == nil && options.HostsFile == "" {
+	// -T - 或未指定目标但有管道输入时，从标准输入读取目标
+	if options.HostsFile == "-" {
+		options.HostsFile = ""
+		options.Stdin = true
+	} else if options.Host == nil && options.HostsFile == "" && fileutil.HasStdin() {
+		options.Stdin = true
+	}
+
+	if options.Host == nil && options.HostsFile == "" && !options.Stdin {
 		return errNoInputList
 	}
 
+	if !importer.IsSupported(options.InputFormat) {
+		return errors.Wrap(errUnsupportedFormat, options.InputFormat)
+	}
+
+	if !retryhttpclient.IsRedirectPolicy(options.FollowRedirect) {
+		return errors.Wrap(errUnsupportedRedirect, options.FollowRedirect)
+	}
+
 	if options.Timeout == 0 {
 		return errors.Wrap(errZeroValue, "timeout")
 	}
 
+	if options.TargetTimeout <= 0 {
+		return errors.Wrap(errZeroValue, "target-timeout")
+	}
+
+	if options.FingerprintTimeout <= 0 {
+		return errors.Wrap(errZeroValue, "fingerprint-timeout")
+	}
+
+	if options.CdnTimeout <= 0 {
+		return errors.Wrap(errZeroValue, "cdn-timeout")
+	}
+
 	if options.RateLimit <= 0 {
 		return errors.Wrap(errZeroValue, "rate")
 	} else if options.RateLimit == DefaultRateLimit {
 		options.autoChangeRateLimit()
 	}
 
+	if options.Adaptive {
+		if err := options.validateAdaptive(); err != nil {
+			return err
+		}
+	}
+
+	if options.ClusterDistance < 0 || options.ClusterDistance > 64 {
+		return errors.New("cluster-distance must be between 0 and 64")
+	}
+
+	if len(options.VhostFile) > 0 {
+		options.Vhost = true
+	}
+
+	if options.KeepWildcard {
+		options.Wildcard = true
+	}
+
+	if options.MaxHostConns < 0 {
+		return errors.New("max-host-conns cannot be negative")
+	}
+
+	if options.MaxIPConns < 0 {
+		return errors.New("max-ip-conns cannot be negative")
+	}
+
+	if options.Stats && options.StatsInterval <= 0 {
+		return errors.Wrap(errZeroValue, "stats-interval")
+	}
+
 	return err
 }
 
+// validateAdaptive 检查自适应速率的上下限，未指定上限时为 -rate 的 4 倍
+func (options *Options) validateAdaptive() error {
+	if options.RateMin <= 0 {
+		return errors.Wrap(errZeroValue, "rate-min")
+	}
+	if options.RateMax == 0 {
+		options.RateMax = max(options.RateLimit*DefaultRateScale, options.RateMin)
+	}
+	if options.RateMax < options.RateMin {
+		return errors.New("rate-max cannot be less than rate-min")
+	}
+	return nil
+}
+
 func (options *Options) autoChangeRateLimit() {
 	NumCPU := runtime.NumCPU()
 
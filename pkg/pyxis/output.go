@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/zan8in/gologger"
 	fileutil2 "github.com/zan8in/pins/file"
@@ -18,6 +20,7 @@ import (
 type OutputResult struct {
 	Flag          int    `json:"flag" csv:"flag"`
 	FullUrl       string `json:"fullurl,omitempty" csv:"fullurl"`
+	FinalUrl      string `json:"finalurl,omitempty" csv:"finalurl"`
 	Host          string `json:"host,omitempty" csv:"host"`
 	IP            string `json:"ip,omitempty" csv:"ip"`
 	Port          int    `json:"port" csv:"port"`
@@ -29,14 +32,46 @@ type OutputResult struct {
 	FaviconHash   string `json:"faviconhash,omitempty" csv:"faviconhash"`
 	Fingerprint   string `json:"fingerprint,omitempty" csv:"fingerprint"`
 	Cdn           string `json:"cdn,omitempty" csv:"cdn"` // 新增CDN字段
+
+	Redirects []result.Redirect `json:"redirects,omitempty" csv:"redirects"`
+	TLSInfo   *result.TLSInfo   `json:"tlsinfo,omitempty" csv:"tlsinfo"`
+
+	TLSFingerprint string `json:"tlsfingerprint,omitempty" csv:"tlsfingerprint"`
+
+	Fingerprints []result.Fingerprint `json:"fingerprints,omitempty" csv:"fingerprints"`
+
+	Timeouts []string `json:"timeouts,omitempty" csv:"timeouts"`
+	Errors   []string `json:"errors,omitempty" csv:"errors"`
+	Failure  string   `json:"failure,omitempty" csv:"failure"`
+
+	Vhost bool `json:"vhost,omitempty" csv:"vhost"`
+
+	BodyMD5       string `json:"bodymd5,omitempty" csv:"bodymd5"`
+	BodySHA256    string `json:"bodysha256,omitempty" csv:"bodysha256"`
+	HeaderSetHash string `json:"headersethash,omitempty" csv:"headersethash"`
+	Simhash       string `json:"simhash,omitempty" csv:"simhash"`
+
+	Wildcard bool `json:"wildcard,omitempty" csv:"wildcard"`
 }
 
 func (r *Runner) print(result *result.HostResult) {
+	if r.Options.NoStdout {
+		return
+	}
+
 	// 如果启用了Clear选项，跳过失败的结果
 	if r.Options.Clear && result.Flag != 0 {
 		return
 	}
 
+	// -json 模式下每行输出一个 JSON 对象，便于管道处理
+	if r.Options.JSON {
+		if b, err := NewOutputResult(result).JSON(); err == nil {
+			fmt.Println(string(b))
+		}
+		return
+	}
+
 	// 如果启用了CDN选项，只显示CDN检测结果
 	if r.Options.Cdn {
 		if result.Flag == 0 {
@@ -56,9 +91,10 @@ func (r *Runner) print(result *result.HostResult) {
 		} else {
 			// 如果启用了Clear，不显示失败结果
 			if !r.Options.Clear {
-				fmt.Printf("%s [%s]\n",
+				fmt.Printf("%s [%s][%s]\n",
 					result.Host,
 					logcolor.LogColor.Failed("Failed to detect"),
+					logcolor.LogColor.Failed(result.Failure),
 				)
 			}
 		}
@@ -66,8 +102,15 @@ func (r *Runner) print(result *result.HostResult) {
 	}
 
 	if result.Flag == 0 {
+		fullUrl := result.FullUrl
+		if len(result.Redirects) > 0 && result.FinalUrl != result.FullUrl {
+			fullUrl += " -> " + result.FinalUrl
+		}
+		if result.Wildcard {
+			fullUrl += " (wildcard)"
+		}
 		fmt.Printf("%s [%s][%s][%s][%s][%s][%s][%s]\n",
-			result.FullUrl,
+			fullUrl,
 			logcolor.LogColor.Status(result.StatusCode),
 			logcolor.LogColor.ContentLength(FormatFileSize(result.ContentLength)),
 			logcolor.LogColor.Title(result.Title),
@@ -79,10 +122,11 @@ func (r *Runner) print(result *result.HostResult) {
 	} else {
 		// 如果启用了Clear，不显示失败结果
 		if !r.Options.Clear {
-			fmt.Printf("%s [%s%s]\n",
+			fmt.Printf("%s [%s%s][%s]\n",
 				result.Host,
 				logcolor.LogColor.Failed("Failed to access "),
 				logcolor.LogColor.Failed(result.Host),
+				logcolor.LogColor.Failed(result.Failure),
 			)
 		}
 	}
@@ -93,6 +137,11 @@ func (r *Runner) WriteOutput() {
 		return
 	}
 
+	// 流式格式已在 Listener 中逐条写入
+	if r.stream != nil {
+		return
+	}
+
 	var (
 		file     *os.File
 		output   string
@@ -125,29 +174,16 @@ func (r *Runner) WriteOutput() {
 	case fileutil.FILE_CSV:
 		csvutil = csv.NewWriter(file)
 		file.WriteString("\xEF\xBB\xBF")
-	// csvutil.Write([]string{"FullURL", "Title", "StatusCode", "Faviconhash", "Fingerprint", "ContentLength", "ResponseTime", "Host", "IP", "Port", "TLS"})
+		csvutil.Write(csvHeader())
 	case fileutil.FILE_JSON:
 		fileutil.BufferWriteAppend(file, "[")
 	}
 
 	for result := range r.Result.GetHostResult() {
-		or := &OutputResult{
-			Flag:          result.Flag,
-			FullUrl:       result.FullUrl,
-			Host:          result.Host,
-			IP:            result.IP,
-			Port:          result.Port,
-			TLS:           result.TLS,
-			Title:         result.Title,
-			StatusCode:    result.StatusCode,
-			FaviconHash:   result.FaviconHash,
-			ContentLength: result.ContentLength,
-			ResponseTime:  result.ResponseTime,
-			Fingerprint:   result.FingerPrint,
-			Cdn:           result.Cdn, // 添加CDN字段
-		}
+		or := NewOutputResult(result)
 
-		if or.Flag == 1 {
+		// 失败结果同样写入输出，-clear 时跳过
+		if or.Flag != 0 && r.Options.Clear {
 			continue
 		}
 
@@ -178,16 +214,74 @@ func (r *Runner) WriteOutput() {
 
 }
 
+// NewOutputResult 将扫描结果转换为输出格式，不包含响应内容
+func NewOutputResult(result *result.HostResult) *OutputResult {
+	return &OutputResult{
+		Flag:          result.Flag,
+		FullUrl:       result.FullUrl,
+		FinalUrl:      result.FinalUrl,
+		Redirects:     result.Redirects,
+		TLSInfo:       result.TLSInfo,
+		Host:          result.Host,
+		IP:            result.IP,
+		Port:          result.Port,
+		TLS:           result.TLS,
+		Title:         result.Title,
+		StatusCode:    result.StatusCode,
+		FaviconHash:   result.FaviconHash,
+		ContentLength: result.ContentLength,
+		ResponseTime:  result.ResponseTime,
+		Fingerprint:   result.FingerPrint,
+		Cdn:           result.Cdn, // 添加CDN字段
+
+		TLSFingerprint: result.TLSFingerprint,
+
+		Fingerprints: result.Fingerprints,
+
+		Timeouts: result.Timeouts,
+		Errors:   result.Errors,
+		Failure:  result.Failure,
+
+		Vhost: result.Vhost,
+
+		BodyMD5:       result.BodyMD5,
+		BodySHA256:    result.BodySHA256,
+		HeaderSetHash: result.HeaderSetHash,
+		Simhash:       result.Simhash,
+
+		Wildcard: result.Wildcard,
+	}
+}
+
 func (or *OutputResult) JSON() ([]byte, error) {
 	return json.Marshal(or)
 }
 
+// TXT 每行为 host、ip、cdn、fullurl 和证书信息，以制表符分隔。
+// 失败结果的列与成功结果相同，在最后追加失败原因，按列号解析的脚本不受影响
 func (or *OutputResult) TXT() string {
-	return fmt.Sprintf("%s\t%s\t%s\t%s\n", or.Host, or.IP, or.Cdn, or.FullUrl)
+	line := fmt.Sprintf("%s\t%s\t%s\t%s\t%s", or.Host, or.IP, or.Cdn, or.FullUrl, or.TLSInfo.String())
+	if or.Flag != 0 {
+		line += "\t" + or.Failure
+	}
+	return line + "\n"
+}
+
+// csvHeader 返回 CSV 的表头，与 CSV 的列一一对应
+func csvHeader() []string {
+	header := []string{
+		"host", "ip", "cdn", "fullurl", "title", "statuscode", "faviconhash", "fingerprint",
+		"contentlength", "responsetime", "port", "tls", "finalurl", "redirects",
+		"subjectcn", "sans", "issuer", "notbefore", "notafter", "serial", "certsha256", "tlsversion", "ciphersuite", "alpn",
+	}
+	return append(header, "tlsfingerprint", "fingerprints",
+		"timeouts", "errors", "failure",
+		"vhost", "bodymd5", "bodysha256", "headersethash", "simhash",
+		"wildcard")
 }
 
 func (or *OutputResult) CSV() []string {
-	return []string{
+	record := []string{
 		or.Host,
 		or.IP,
 		or.Cdn,
@@ -200,7 +294,56 @@ func (or *OutputResult) CSV() []string {
 		fmt.Sprintf("%d", or.ResponseTime),
 		strconv.Itoa(or.Port),
 		fmt.Sprintf("%t", or.TLS),
+		or.FinalUrl,
+		or.redirectChain(),
+	}
+	record = append(record, or.tlsRecord()...)
+	return append(record, or.TLSFingerprint, or.fingerprintsRecord(),
+		strings.Join(or.Timeouts, ";"), strings.Join(or.Errors, ";"), or.Failure,
+		strconv.FormatBool(or.Vhost), or.BodyMD5, or.BodySHA256, or.HeaderSetHash, or.Simhash,
+		strconv.FormatBool(or.Wildcard))
+}
+
+// fingerprintsRecord 将结构化指纹编码为 JSON 数组写入单个 CSV 单元格
+func (or *OutputResult) fingerprintsRecord() string {
+	if len(or.Fingerprints) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(or.Fingerprints); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func (or *OutputResult) tlsRecord() []string {
+	t := or.TLSInfo
+	if t == nil {
+		return make([]string, 10)
+	}
+	return []string{
+		t.SubjectCN,
+		strings.Join(t.SANs, ","),
+		t.Issuer,
+		t.NotBefore.Format(time.RFC3339),
+		t.NotAfter.Format(time.RFC3339),
+		t.Serial,
+		t.SHA256,
+		t.Version,
+		t.CipherSuite,
+		t.ALPN,
+	}
+}
+
+// redirectChain 将跳转链格式化为 "301 http://a -> 302 http://b" 形式
+func (or *OutputResult) redirectChain() string {
+	hops := make([]string, 0, len(or.Redirects))
+	for _, hop := range or.Redirects {
+		hops = append(hops, fmt.Sprintf("%d %s", hop.StatusCode, hop.URL))
 	}
+	return strings.Join(hops, " -> ")
 }
 
 func FormatFileSize(fileSize int64) (size string) {
@@ -0,0 +1,35 @@
+package pyxis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zan8in/pyxis/pkg/result"
+)
+
+func TestCSVHeader(t *testing.T) {
+	for _, rst := range []*result.HostResult{
+		{Host: "example.com", FullUrl: "https://example.com"},
+		{Host: "example.com", FullUrl: "https://example.com", TLSInfo: &result.TLSInfo{SubjectCN: "example.com"}},
+		{Host: "example.com", Flag: 1, Failure: result.FailureTimeout},
+	} {
+		if got, want := len(NewOutputResult(rst).CSV()), len(csvHeader()); got != want {
+			t.Errorf("%+v: %d columns, header has %d", rst, got, want)
+		}
+	}
+}
+
+// 失败结果的 TXT 行与成功结果的列相同，只在最后追加失败原因
+func TestTXTColumns(t *testing.T) {
+	ok := NewOutputResult(&result.HostResult{Host: "a.test", IP: "10.0.0.1", FullUrl: "https://a.test"}).TXT()
+	failed := NewOutputResult(&result.HostResult{Host: "b.test", Flag: 1, Failure: result.FailureDNS}).TXT()
+
+	okCols := strings.Split(strings.TrimSuffix(ok, "\n"), "\t")
+	failedCols := strings.Split(strings.TrimSuffix(failed, "\n"), "\t")
+	if len(failedCols) != len(okCols)+1 {
+		t.Fatalf("failed line has %d columns, want %d: %q", len(failedCols), len(okCols)+1, failed)
+	}
+	if failedCols[0] != "b.test" || failedCols[len(failedCols)-1] != result.FailureDNS {
+		t.Errorf("unexpected failed line %q", failed)
+	}
+}
@@ -0,0 +1,114 @@
+package pyxis
+
+import (
+	"bufio"
+	"context"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/zan8in/pyxis/pkg/result"
+)
+
+// loadPaths 合并 -path 和 -path-file 指定的路径，去重并补全前导 /
+func loadPaths(options *Options) ([]string, error) {
+	var (
+		paths []string
+		seen  = make(map[string]struct{})
+	)
+
+	add := func(path string) {
+		path = strings.TrimSpace(path)
+		if len(path) == 0 {
+			return
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		// 根路径在协议探测时已经请求过
+		if path == "/" {
+			return
+		}
+		if _, ok := seen[path]; ok {
+			return
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
+	}
+
+	for _, path := range options.Paths {
+		add(path)
+	}
+
+	if len(options.PathFile) > 0 {
+		f, err := os.Open(options.PathFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			add(s.Text())
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+// ScanPath 复用源站的协议探测结果，请求源站下的指定路径
+func (r *Runner) ScanPath(ctx context.Context, origin *result.HostResult, path string) (result.HostResult, error) {
+	var rst result.HostResult
+
+	u, err := url.Parse(origin.FullUrl)
+	if err != nil {
+		return rst, err
+	}
+
+	rst, err = r.client.ProbeContext(ctx, u.Scheme+"://"+u.Host+path)
+	if err != nil {
+		return rst, err
+	}
+
+	rst.Host = origin.Host
+	rst.IP = origin.IP
+	rst.Cdn = origin.Cdn
+	r.enrich(ctx, &rst, origin)
+
+	return rst, nil
+}
+
+// enrich 补全路径和虚拟主机请求的结果：沿用源站的端口、协议和 TLS 指纹，计算 favicon 并识别指纹
+func (r *Runner) enrich(ctx context.Context, rst, origin *result.HostResult) {
+	rst.Port = origin.Port
+	rst.TLS = origin.TLS
+	rst.FaviconHash = r.favicon.FaviconHash(ctx, rst.FullUrl, rst.Body)
+	r.getFingerprintAsync(ctx, rst)
+	libraFingerprints(rst)
+	rst.TLSFingerprint = origin.TLSFingerprint
+	r.matchTLSFingerprint(rst)
+	r.matchFingerprintRules(rst)
+}
+
+// scanPaths 对存活的源站请求配置的路径，每个路径单独输出结果，未完成的路径记录在源站结果的 Errors 中。
+// -cdn 的结果没有协议，不扫描路径
+func (r *Runner) scanPaths(origin *result.HostResult) {
+	if len(r.paths) == 0 || r.Options.Cdn {
+		return
+	}
+	if u, err := url.Parse(origin.FullUrl); err != nil || len(u.Scheme) == 0 {
+		return
+	}
+
+	r.subscan(origin, result.StagePath, r.paths, func(ctx context.Context, path string) error {
+		rst, err := r.ScanPath(ctx, origin, path)
+		if err != nil {
+			return err
+		}
+		r.sendResult(&rst)
+		return nil
+	})
+}
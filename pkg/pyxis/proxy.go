@@ -0,0 +1,115 @@
+package pyxis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zan8in/cdncheck"
+	"github.com/zan8in/godns"
+	"github.com/zan8in/pyxis/pkg/proxypool"
+)
+
+// newProxyPool 按 -proxy 创建代理池，设置了 ProxyPool 时直接使用，未配置代理时返回 nil
+func newProxyPool(options *Options) (*proxypool.Pool, error) {
+	if options.ProxyPool != nil {
+		return options.ProxyPool, nil
+	}
+	if len(options.Proxy) == 0 {
+		return nil, nil
+	}
+	return proxypool.New(options.Proxy, options.ProxyMode, time.Duration(options.ProxyRetry)*time.Second)
+}
+
+// newCDNCheckers 创建 CDN 检查器，配置了代理池时为每个代理创建一个，检测时和 HTTP 请求一样轮换
+func newCDNCheckers(options *Options, proxies *proxypool.Pool) (*cdncheck.CDNChecker, map[*proxypool.Proxy]*cdncheck.CDNChecker, error) {
+	if proxies == nil {
+		cdnchecker, err := newCDNChecker(options, nil)
+		return cdnchecker, nil, err
+	}
+
+	cdncheckers := make(map[*proxypool.Proxy]*cdncheck.CDNChecker, proxies.Len())
+	for _, p := range proxies.Proxies() {
+		cdnchecker, err := newCDNChecker(options, p.URL)
+		if err != nil {
+			return nil, nil, err
+		}
+		cdncheckers[p] = cdnchecker
+	}
+	return nil, cdncheckers, nil
+}
+
+func newCDNChecker(options *Options, proxyURL *url.URL) (*cdncheck.CDNChecker, error) {
+	// 构建基础配置选项
+	opts := []cdncheck.Option{
+		cdncheck.WithRetries(options.Retries),
+		cdncheck.WithTimeout(time.Duration(options.Timeout) * time.Second),
+		cdncheck.WithDoH(),
+	}
+
+	// 处理代理配置
+	if proxyURL != nil {
+		// 提取认证信息
+		var auth *godns.ProxyAuth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &godns.ProxyAuth{
+				Username: proxyURL.User.Username(),
+				Password: password,
+			}
+		}
+
+		// 根据代理类型添加相应选项
+		switch strings.ToLower(proxyURL.Scheme) {
+		case "socks5", "socks5h":
+			opts = append(opts, cdncheck.WithSOCKS5Proxy(proxyURL.Host, auth))
+		case "http", "https":
+			opts = append(opts, cdncheck.WithHTTPProxy(proxyURL.Host, auth))
+		default:
+			return nil, fmt.Errorf("不支持的代理类型: %s，支持的类型: http, https, socks5", proxyURL.Scheme)
+		}
+	}
+
+	cdnchecker := cdncheck.New(opts...)
+
+	// 检查cdnchecker是否创建成功
+	if cdnchecker == nil {
+		return nil, fmt.Errorf("创建CDN检查器失败")
+	}
+	return cdnchecker, nil
+}
+
+// cdnChecker 返回本次检测使用的 CDN 检查器和它使用的代理，未配置代理时代理为 nil
+func (r *Runner) cdnChecker() (*cdncheck.CDNChecker, *proxypool.Proxy) {
+	if r.proxies == nil {
+		return r.cdnchecker, nil
+	}
+	p := r.proxies.Next()
+	return r.cdncheckers[p], p
+}
+
+// markCDNProxy 按域名的 CDN 检测结果更新代理状态。cdncheck 不返回查询错误，
+// 代理不可用和域名无法解析都表现为没有 IP，此时连接一次代理确认
+func (r *Runner) markCDNProxy(ctx context.Context, p *proxypool.Proxy, rst *cdncheck.CheckResult) {
+	if p == nil {
+		return
+	}
+	if rst != nil && len(rst.IPs) > 0 {
+		r.proxies.MarkOK(p)
+		return
+	}
+
+	dialer := net.Dialer{Timeout: time.Duration(r.Options.Timeout) * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", p.Addr())
+	if err != nil {
+		// 目标的 ctx 超时或取消不说明代理不可用
+		if ctx.Err() == nil {
+			r.proxies.MarkFailed(p)
+		}
+		return
+	}
+	conn.Close()
+}
@@ -0,0 +1,165 @@
+package pyxis
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zan8in/gologger"
+	"github.com/zan8in/pyxis/pkg/http/retryhttpclient"
+	"github.com/zan8in/pyxis/pkg/result"
+)
+
+const (
+	rateWindow     = 2 * time.Second // 每个统计窗口的时长
+	rateMinSamples = 5               // 窗口内样本不足时不调整
+
+	rateBadRatio      = 0.05 // 异常比例超过 5% 时减半
+	rateSlowFactor    = 2    // 平均延迟超过基线 2 倍时降低 1/4
+	rateIncreaseStep  = 10   // 健康时每个窗口提高 1/10，至少 1
+	rateBaselineDecay = 0.2  // 每个窗口的平均延迟在基线中的权重
+)
+
+// rateLimiter 控制每秒投递的目标数。-adaptive 时按每次 HTTP 请求的错误和延迟调整速率：
+// 窗口内超时、连接重置、429 或代理错误较多时减半，延迟明显升高时降低，健康时逐步提高，
+// 速率始终在 [-rate-min, -rate-max] 之间。
+// 超时和连接重置只在地址最近有过响应时计入，过滤端口和死主机本来就会超时，不代表拥塞
+type rateLimiter struct {
+	mu     sync.Mutex
+	ticker *time.Ticker
+
+	rate     int
+	min, max int
+	adaptive bool
+
+	// 当前窗口的统计
+	start    time.Time
+	total    int
+	bad      int
+	ok       int
+	latency  time.Duration
+	baseline time.Duration // 非异常窗口平均延迟的指数移动平均
+
+	// 当前和上一个窗口中有过响应的地址，只保留两个窗口，内存不随目标数增长
+	alive, lastAlive map[string]struct{}
+}
+
+func newRateLimiter(options *Options) *rateLimiter {
+	l := &rateLimiter{
+		rate:     options.RateLimit,
+		min:      options.RateMin,
+		max:      options.RateMax,
+		adaptive: options.Adaptive,
+		start:    time.Now(),
+		alive:    make(map[string]struct{}),
+	}
+	if l.adaptive {
+		l.rate = min(max(l.rate, l.min), l.max)
+	}
+	l.ticker = time.NewTicker(time.Second / time.Duration(l.rate))
+	return l
+}
+
+// Wait 等待下一次投递
+func (l *rateLimiter) Wait() {
+	<-l.ticker.C
+}
+
+// Rate 返回当前速率
+func (l *rateLimiter) Rate() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// ObserveAttempt 记录一次 HTTP 请求尝试，窗口结束时调整速率
+func (l *rateLimiter) ObserveAttempt(attempt retryhttpclient.Attempt) {
+	if l == nil || !l.adaptive {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case attempt.Err == nil:
+		l.alive[attempt.Addr] = struct{}{}
+		if attempt.StatusCode == http.StatusTooManyRequests {
+			l.bad++
+		} else {
+			l.ok++
+			l.latency += attempt.Latency
+		}
+	case l.congested(attempt):
+		l.bad++
+	default:
+		return
+	}
+	l.total++
+
+	if l.total < rateMinSamples || time.Since(l.start) < rateWindow {
+		return
+	}
+	l.adjust()
+}
+
+// congested 判断失败的请求是否说明网络或目标过载，调用方需持有锁
+func (l *rateLimiter) congested(attempt retryhttpclient.Attempt) bool {
+	switch result.Classify(attempt.Err) {
+	case result.FailureProxy:
+		return true
+	case result.FailureTimeout, result.FailureHTTP:
+		_, ok := l.alive[attempt.Addr]
+		if !ok {
+			_, ok = l.lastAlive[attempt.Addr]
+		}
+		return ok
+	}
+	return false
+}
+
+// adjust 按窗口统计调整速率并开始新窗口，调用方需持有锁
+func (l *rateLimiter) adjust() {
+	var avg time.Duration
+	if l.ok > 0 {
+		avg = l.latency / time.Duration(l.ok)
+	}
+
+	rate := l.rate
+	bad := float64(l.bad)/float64(l.total) > rateBadRatio
+	switch {
+	case bad:
+		rate = rate / 2
+	case l.baseline > 0 && avg > l.baseline*rateSlowFactor:
+		rate = rate * 3 / 4
+	default:
+		rate += max(1, rate/rateIncreaseStep)
+	}
+	// 基线随窗口移动，偶尔一个很快的窗口不会让之后的窗口一直被判为变慢，
+	// 延迟持续升高时基线也会跟上，不会无限降速。异常窗口的延迟不可靠，不计入
+	if avg > 0 && !bad {
+		if l.baseline == 0 {
+			l.baseline = avg
+		} else {
+			l.baseline += time.Duration(rateBaselineDecay * float64(avg-l.baseline))
+		}
+	}
+	rate = min(max(rate, l.min), l.max)
+
+	if rate != l.rate {
+		gologger.Debug().Msgf("rate %d/s -> %d/s (bad %d/%d, latency %s)", l.rate, rate, l.bad, l.total, avg)
+		l.rate = rate
+		l.ticker.Reset(time.Second / time.Duration(rate))
+	}
+
+	l.start = time.Now()
+	l.total, l.bad, l.ok, l.latency = 0, 0, 0, 0
+	l.lastAlive, l.alive = l.alive, make(map[string]struct{})
+}
+
+func (l *rateLimiter) Stop() {
+	l.ticker.Stop()
+}
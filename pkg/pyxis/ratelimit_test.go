@@ -0,0 +1,41 @@
+package pyxis
+
+import (
+	"testing"
+	"time"
+)
+
+// testWindow 模拟一个全部成功、平均延迟为 latency 的窗口，返回调整后的速率
+func testWindow(l *rateLimiter, latency time.Duration) int {
+	l.total, l.ok, l.latency = 10, 10, 10*latency
+	l.adjust()
+	return l.rate
+}
+
+func TestRateLimiterBaselineRecovers(t *testing.T) {
+	l := newRateLimiter(&Options{RateLimit: 100, RateMin: 1, RateMax: 1000, Adaptive: true})
+	defer l.Stop()
+
+	// 一个很快的窗口之后延迟恢复正常，速率不应一直下降
+	testWindow(l, 10*time.Millisecond)
+	for i := 0; i < 10; i++ {
+		testWindow(l, 100*time.Millisecond)
+	}
+	before := l.rate
+	if after := testWindow(l, 100*time.Millisecond); after <= before {
+		t.Fatalf("rate still decreasing at steady latency: %d -> %d (baseline %s)", before, after, l.baseline)
+	}
+}
+
+func TestRateLimiterSlowWindow(t *testing.T) {
+	l := newRateLimiter(&Options{RateLimit: 100, RateMin: 1, RateMax: 1000, Adaptive: true})
+	defer l.Stop()
+
+	for i := 0; i < 5; i++ {
+		testWindow(l, 50*time.Millisecond)
+	}
+	before := l.rate
+	if after := testWindow(l, 500*time.Millisecond); after >= before {
+		t.Fatalf("latency spike did not lower the rate: %d -> %d", before, after)
+	}
+}
@@ -3,34 +3,60 @@ package pyxis
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/remeh/sizedwaitgroup"
 	"github.com/zan8in/cdncheck"
-	"github.com/zan8in/godns"
 	"github.com/zan8in/gologger"
 	"github.com/zan8in/libra"
 	"github.com/zan8in/pyxis/pkg/favicon"
+	"github.com/zan8in/pyxis/pkg/fingerprint"
 	"github.com/zan8in/pyxis/pkg/http/retryhttpclient"
+	"github.com/zan8in/pyxis/pkg/proxypool"
 	"github.com/zan8in/pyxis/pkg/result"
 	"github.com/zan8in/pyxis/pkg/util/iputil"
+	"github.com/zan8in/pyxis/pkg/util/portutil"
 )
 
-var defaultCdncheckTimeout = 3
-
 type Runner struct {
 	Options *Options
 
-	ticker *time.Ticker
-	wgscan sizedwaitgroup.SizedWaitGroup
+	limiter *rateLimiter
+	cluster *clusterer
+	wgscan  sizedwaitgroup.SizedWaitGroup
 
 	hostChan chan string
+	ports    []int
+	paths    []string
+	vhosts   []string
+	inflight sync.WaitGroup // 未完成的目标数，归零后关闭 hostChan
+	enqueued sync.Map       // 扫描过程中追加的目标，用于去重
+	tlsfpDB  map[string]string
+	rules    []*fingerprint.Rule
+
+	stream *streamWriter
+
+	wildcards sync.Map // 父域名、协议和端口到 *wildcardBaseline
+
+	client  *retryhttpclient.Client
+	favicon *favicon.Fetcher
+
+	ctx        context.Context // 扫描上下文，取消后中止进行中的请求
+	stopCtx    context.Context // 停止投递新目标，Stop 或 ctx 取消时触发
+	stop       context.CancelFunc
+	position   int // 已读取的输入行数
+	checkpoint *checkpoint
+
+	queued  atomic.Int64 // 已投递的目标数
+	scanned atomic.Int64 // 已完成扫描的目标数
 
 	ResultChan chan *result.HostResult
 	Result     *result.Result
@@ -39,82 +65,97 @@ type Runner struct {
 
 	Phase Phase
 
-	cdnchecker *cdncheck.CDNChecker
+	cdnchecker  *cdncheck.CDNChecker
+	cdncheckers map[*proxypool.Proxy]*cdncheck.CDNChecker // 代理池中每个代理对应的 CDN 检查器
+	proxies     *proxypool.Pool
 
 	// 新增：指纹识别专用并发控制
 	fingerprintSemaphore chan struct{}
 }
 
+// Stats 扫描进度，Queued 随 CIDR/端口展开和 SAN 追加持续增长，Rate 为当前每秒投递的目标数
+type Stats struct {
+	Queued  int64 `json:"queued"`
+	Scanned int64 `json:"scanned"`
+	Rate    int   `json:"rate"`
+}
+
 func NewRunner(options *Options) (*Runner, error) {
 	var (
 		err error
 	)
 
-	var cdnchecker *cdncheck.CDNChecker
+	proxies, err := newProxyPool(options)
+	if err != nil {
+		return nil, err
+	}
 
-	// 构建基础配置选项
-	opts := []cdncheck.Option{
-		cdncheck.WithRetries(options.Retries),
-		cdncheck.WithTimeout(time.Duration(options.Timeout) * time.Second),
-		cdncheck.WithDoH(),
+	cdnchecker, cdncheckers, err := newCDNCheckers(options, proxies)
+	if err != nil {
+		return nil, err
 	}
 
-	// 处理代理配置
-	if options.Proxy != "" {
-		proxyURL, err := url.Parse(options.Proxy)
-		if err != nil {
-			return nil, fmt.Errorf("解析代理URL失败: %v", err)
-		}
+	ports, err := portutil.ParsePorts(options.Ports)
+	if err != nil {
+		return nil, err
+	}
 
-		// 提取认证信息
-		var auth *godns.ProxyAuth
-		if proxyURL.User != nil {
-			password, _ := proxyURL.User.Password()
-			auth = &godns.ProxyAuth{
-				Username: proxyURL.User.Username(),
-				Password: password,
-			}
-		}
+	paths, err := loadPaths(options)
+	if err != nil {
+		return nil, err
+	}
 
-		// 根据代理类型添加相应选项
-		switch strings.ToLower(proxyURL.Scheme) {
-		case "socks5":
-			opts = append(opts, cdncheck.WithSOCKS5Proxy(proxyURL.Host, auth))
-		case "http", "https":
-			opts = append(opts, cdncheck.WithHTTPProxy(proxyURL.Host, auth))
-		default:
-			return nil, fmt.Errorf("不支持的代理类型: %s，支持的类型: http, https, socks5", proxyURL.Scheme)
-		}
+	vhosts, err := loadVhosts(options)
+	if err != nil {
+		return nil, err
 	}
 
-	cdnchecker = cdncheck.New(opts...)
+	tlsfpDB, err := loadTLSFingerprintDB(options.TLSFingerprintDB)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := loadFingerprintRules(options.FingerprintFile)
+	if err != nil {
+		return nil, err
+	}
 
-	// 检查cdnchecker是否创建成功
-	if cdnchecker == nil {
-		return nil, fmt.Errorf("创建CDN检查器失败")
+	checkpoint, err := loadCheckpoint(options)
+	if err != nil {
+		return nil, err
 	}
 
 	runner := &Runner{
 		Options:    options,
+		checkpoint: checkpoint,
 		hostChan:   make(chan string),
+		ports:      ports,
+		paths:      paths,
+		vhosts:     vhosts,
+		cluster:    newClusterer(options),
+		tlsfpDB:    tlsfpDB,
+		rules:      rules,
 		ResultChan: make(chan *result.HostResult),
 		Result:     result.NewResult(),
 		cdnchecker: cdnchecker,
+		proxies:    proxies,
+
+		cdncheckers: cdncheckers,
 
 		// 指纹识别并发限制为主并发的1/4，避免CPU过载
 		fingerprintSemaphore: make(chan struct{}, calculateFingerprintConcurrency(options.RateLimit)),
 	}
 
-	if err = retryhttpclient.Init(&retryhttpclient.Options{
-		Retries: options.Retries,
-		Timeout: options.Timeout,
-		Proxy:   options.Proxy,
-	}); err != nil {
+	// 客户端的每次请求都反馈给 limiter，需先于客户端创建
+	runner.limiter = newRateLimiter(options)
+	if err = runner.initHTTPClient(); err != nil {
 		return runner, err
 	}
 
-	runner.wgscan = sizedwaitgroup.New(options.RateLimit)
-	runner.ticker = time.NewTicker(time.Second / time.Duration(options.RateLimit))
+	runner.ctx = context.Background()
+	runner.stopCtx, runner.stop = context.WithCancel(context.Background())
+	// 自适应速率时并发上限按最大速率设置
+	runner.wgscan = sizedwaitgroup.New(max(options.RateLimit, options.RateMax))
 
 	return runner, err
 }
@@ -128,25 +169,72 @@ func NewApiRunner(options *Options) (*Runner, error) {
 		Options: options,
 	}
 
-	if err = retryhttpclient.Init(&retryhttpclient.Options{
-		Retries: options.Retries,
-		Timeout: options.Timeout,
-		Proxy:   options.Proxy,
-	}); err != nil {
+	if runner.proxies, err = newProxyPool(options); err != nil {
+		return runner, err
+	}
+
+	if err = runner.initHTTPClient(); err != nil {
 		return runner, err
 	}
 
 	return runner, err
 }
 
+// initHTTPClient 创建 Runner 独占的 HTTP 客户端和 favicon 请求器，不修改包级全局状态
+func (r *Runner) initHTTPClient() error {
+	client, err := retryhttpclient.New(&retryhttpclient.Options{
+		Retries:  r.Options.Retries,
+		Timeout:  r.Options.Timeout,
+		Redirect: r.Options.FollowRedirect,
+
+		ProxyPool: r.proxies,
+
+		Method:    r.Options.Method,
+		Body:      r.Options.Body,
+		Headers:   r.Options.Headers,
+		Cookie:    r.Options.Cookie,
+		UserAgent: r.Options.UserAgent,
+
+		MaxHostConns: r.Options.MaxHostConns,
+		MaxIPConns:   r.Options.MaxIPConns,
+
+		OnAttempt: r.onAttempt(),
+	})
+	if err != nil {
+		return err
+	}
+	r.client = client
+	r.favicon = favicon.NewFetcher(client)
+	return nil
+}
+
+// onAttempt 返回客户端每次请求后的回调，只在 -adaptive 时反馈给 limiter
+func (r *Runner) onAttempt() func(retryhttpclient.Attempt) {
+	if r.limiter == nil || !r.Options.Adaptive {
+		return nil
+	}
+	return r.limiter.ObserveAttempt
+}
+
 func (r *Runner) Run() error {
+	return r.RunContext(context.Background())
+}
+
+// RunContext 与 Run 相同，ctx 取消时停止投递并中止进行中的扫描，返回 ctx 的错误
+func (r *Runner) RunContext(ctx context.Context) error {
 	defer r.Close()
 
-	go func() {
-		if err := r.PreprocessHost(); err != nil {
-			gologger.Error().Msg(err.Error())
-		}
-	}()
+	r.bindContext(ctx)
+
+	if err := r.openStream(); err != nil {
+		return err
+	}
+
+	stopSave := make(chan struct{})
+	go r.checkpoint.autoSave(stopSave)
+	go r.printStats(stopSave)
+
+	go r.preprocess()
 
 	// 使用 WaitGroup 等待 Listener 完成
 	listenerWg := sync.WaitGroup{}
@@ -164,18 +252,50 @@ func (r *Runner) Run() error {
 	listenerWg.Wait()
 
 	r.WriteOutput()
+	r.writeClusters()
 
-	return nil
+	close(stopSave)
+	r.checkpoint.close(r.stopCtx.Err() != nil)
+
+	return ctx.Err()
 }
 
-func (r *Runner) ApiRun() error {
-	defer r.Close()
+// Stop 停止投递新目标，已开始的扫描完成并写入输出后 Run 返回
+func (r *Runner) Stop() {
+	if r.stop != nil {
+		r.stop()
+	}
+}
 
+// bindContext 绑定调用方的上下文，ctx 取消时同时停止投递
+func (r *Runner) bindContext(ctx context.Context) {
+	r.ctx = ctx
 	go func() {
-		if err := r.PreprocessHost(); err != nil {
-			gologger.Error().Msg(err.Error())
+		select {
+		case <-ctx.Done():
+			r.Stop()
+		case <-r.stopCtx.Done():
 		}
 	}()
+}
+
+func (r *Runner) preprocess() {
+	if err := r.PreprocessHost(); err != nil {
+		gologger.Error().Msg(err.Error())
+	}
+}
+
+func (r *Runner) ApiRun() error {
+	return r.ApiRunContext(context.Background())
+}
+
+// ApiRunContext 与 ApiRun 相同，ctx 取消时停止扫描并返回 ctx 的错误
+func (r *Runner) ApiRunContext(ctx context.Context) error {
+	defer r.Close()
+
+	r.bindContext(ctx)
+
+	go r.preprocess()
 
 	go r.ApiListener()
 
@@ -183,7 +303,7 @@ func (r *Runner) ApiRun() error {
 
 	r.Delay()
 
-	return nil
+	return ctx.Err()
 }
 
 func (r *Runner) Delay() {
@@ -203,34 +323,77 @@ func (r *Runner) Delay() {
 
 func (r *Runner) Listener() {
 	for result := range r.ResultChan {
-		r.Result.SetHostResult(result.FullUrl, result)
+		r.cluster.Add(result)
+		if r.stream == nil {
+			r.Result.SetHostResult(result.Key(), result)
+			r.print(result)
+			r.onResult(result)
+			continue
+		}
+
+		// 流式输出只记录已输出结果的 key 用于去重，不保留完整结果
+		if result.Flag == 0 && !r.Result.Mark(result.Key()) {
+			continue
+		}
 		r.print(result)
+		r.onResult(result)
+		// 失败结果同样写入输出，-clear 时跳过
+		if result.Flag != 0 && r.Options.Clear {
+			continue
+		}
+		if err := r.stream.Write(result); err != nil {
+			gologger.Error().Msgf("Could not write output %s: %s\n", r.stream.output, err)
+		}
 	}
 	r.Phase.Set(Done)
 }
 
+// ApiListener 接收结果，设置了 Options.OnResult 时结果只交给回调，不在 Result 中保留
 func (r *Runner) ApiListener() {
 	for result := range r.ResultChan {
-		r.Result.SetHostResult(result.FullUrl, result)
+		if r.Options.OnResult == nil {
+			r.Result.SetHostResult(result.Key(), result)
+		}
 		r.print(result)
+		r.onResult(result)
 	}
 	r.Phase.Set(Done)
 }
 
+// onResult 将结果交给 Options.OnResult 回调，回调在 Listener 中逐个调用
+func (r *Runner) onResult(rst *result.HostResult) {
+	if r.Options.OnResult != nil {
+		r.Options.OnResult(rst)
+	}
+}
+
 func (r *Runner) start() {
 	defer close(r.ResultChan)
 	r.Phase.Set(Scan)
 
-	for host := range r.hostChan {
-		// 等待 ticker，控制请求速率
-		<-r.ticker.C
+loop:
+	for {
+		var host string
+		select {
+		case <-r.stopCtx.Done():
+			break loop
+		case h, ok := <-r.hostChan:
+			if !ok {
+				break loop
+			}
+			host = h
+		}
+
+		// 等待 limiter，控制请求速率
+		r.limiter.Wait()
 
 		r.wgscan.Add()
 		go func(host string) {
 			defer r.wgscan.Done()
+			defer r.inflight.Done()
 
-			// 为每个target设置全局超时（30秒）
-			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			// 为每个target设置全局超时（-target-timeout）
+			ctx, cancel := context.WithTimeout(r.ctx, time.Duration(r.Options.TargetTimeout)*time.Second)
 			defer cancel()
 
 			// 使用channel接收结果，支持超时控制
@@ -238,7 +401,7 @@ func (r *Runner) start() {
 			errorChan := make(chan error, 1)
 
 			go func() {
-				if rst, err := r.ScanHost(host); err == nil {
+				if rst, err := r.ScanHostContext(ctx, host); err == nil {
 					resultChan <- rst
 				} else {
 					errorChan <- err
@@ -247,20 +410,133 @@ func (r *Runner) start() {
 
 			select {
 			case rst := <-resultChan:
-				r.ResultChan <- &rst
-			case <-errorChan:
-				r.ResultChan <- &result.HostResult{Host: host, Flag: 1}
+				r.tlsFingerprint(ctx, &rst)
+				r.matchFingerprintRules(&rst)
+				// 泛解析的落地页不再扫描路径、SAN 和虚拟主机
+				if r.dropWildcard(ctx, &rst) {
+					break
+				}
+				// 路径和虚拟主机有各自的超时，未完成的记录在源站结果中，完成后再输出源站
+				r.scanPaths(&rst)
+				r.scanVhosts(&rst)
+				r.sendResult(&rst)
+				r.feedSANs(&rst)
+			case err := <-errorChan:
+				// 被取消的扫描不算失败，也不记入断点，续扫时重新扫描
+				if r.ctx.Err() != nil {
+					return
+				}
+				rst := &result.HostResult{Host: host}
+				rst.Fail(err)
+				r.ResultChan <- rst
 			case <-ctx.Done():
+				if r.ctx.Err() != nil {
+					return
+				}
 				// 超时处理
 				gologger.Warning().Msgf("Target %s 扫描超时，跳过", host)
-				r.ResultChan <- &result.HostResult{Host: host, Flag: 1}
+				rst := &result.HostResult{Host: host, Flag: 1, Failure: result.FailureTimeout, Timeouts: []string{result.StageTarget}}
+				r.ResultChan <- rst
 			}
+			r.checkpoint.finish(host)
+			r.scanned.Add(1)
 		}(host)
 	}
 	r.wgscan.Wait()
 }
 
+// Stats 返回当前扫描进度，可在扫描过程中并发调用
+func (r *Runner) Stats() Stats {
+	return Stats{
+		Queued:  r.queued.Load(),
+		Scanned: r.scanned.Load(),
+		Rate:    r.limiter.Rate(),
+	}
+}
+
+// printStats 每隔 -stats-interval 秒输出一次扫描进度和当前速率
+func (r *Runner) printStats(stop <-chan struct{}) {
+	if !r.Options.Stats {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(r.Options.StatsInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			stats := r.Stats()
+			gologger.Info().Msgf("Scanned %d/%d targets, rate %d/s", stats.Scanned, stats.Queued, stats.Rate)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// sendResult 将结果交给 Listener，-stream 模式下先丢弃指纹识别后不再需要的响应内容
+func (r *Runner) sendResult(rst *result.HostResult) {
+	rst.Hash()
+	if r.Options.Stream {
+		rst.Compact()
+	}
+	r.ResultChan <- rst
+}
+
+// subscanWorkers 每个目标同时进行的虚拟主机或路径请求数
+const subscanWorkers = 4
+
+// subscan 并发请求目标的后续项目（虚拟主机、路径），每项有单独的 -target-timeout 预算，
+// 不受目标自身超时的影响。在目标的扫描协程中调用，总并发仍受 wgscan 限制。
+// 失败的项目记录在 origin 中，扫描被取消时不记录
+func (r *Runner) subscan(origin *result.HostResult, stage string, items []string, scan func(ctx context.Context, item string) error) {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		ch = make(chan string)
+	)
+
+	for i := 0; i < min(subscanWorkers, len(items)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range ch {
+				ctx, cancel := context.WithTimeout(r.ctx, time.Duration(r.Options.TargetTimeout)*time.Second)
+				err := scan(ctx, item)
+				cancel()
+				if err != nil && r.ctx.Err() == nil {
+					mu.Lock()
+					origin.AddItemError(stage, item, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, item := range items {
+		if r.ctx.Err() != nil {
+			break
+		}
+		ch <- item
+	}
+	close(ch)
+	wg.Wait()
+}
+
 func (r *Runner) ScanHost(host string) (result.HostResult, error) {
+	return r.ScanHostContext(context.Background(), host)
+}
+
+// ScanHostContext 与 ScanHost 相同，ctx 取消时中止所有请求
+func (r *Runner) ScanHostContext(ctx context.Context, host string) (result.HostResult, error) {
+	rst, err := r.scanHost(ctx, host)
+	if err == nil {
+		libraFingerprints(&rst)
+	}
+	return rst, err
+}
+
+func (r *Runner) scanHost(ctx context.Context, host string) (result.HostResult, error) {
 	if len(strings.TrimSpace(host)) == 0 {
 		return result.HostResult{}, fmt.Errorf("host %q is empty", host)
 	}
@@ -297,7 +573,7 @@ func (r *Runner) ScanHost(host string) (result.HostResult, error) {
 
 		// 只进行CDN检测
 		result.Host = parseHost
-		result.IP, result.Cdn, err = r.GetDomainIPWithCDN(parseHost)
+		result.IP, result.Cdn, err = r.GetDomainIPWithCDNContext(ctx, parseHost)
 		if err != nil {
 			result.Flag = 1 // 标记为失败
 			return result, err
@@ -307,7 +583,7 @@ func (r *Runner) ScanHost(host string) (result.HostResult, error) {
 	}
 
 	if strings.HasPrefix(host, HTTPS_PREFIX) {
-		result, err = retryhttpclient.Get(host)
+		result, err = r.client.ProbeContext(ctx, host)
 		if err != nil {
 			return result, err
 		}
@@ -317,20 +593,21 @@ func (r *Runner) ScanHost(host string) (result.HostResult, error) {
 		u, err := url.Parse(host)
 		if err == nil {
 			result.Host = u.Hostname()
-			if ip, cdn, err := r.GetDomainIPWithCDN(u.Hostname()); err == nil {
+			result.Port = urlPort(u, 443)
+			if ip, cdn, err := r.GetDomainIPWithCDNContext(ctx, u.Hostname()); err == nil {
 				result.IP = ip
 				result.Cdn = cdn
 			} else {
-				gologger.Warning().Msgf("Failed to get CDN info for %s: %v", u.Hostname(), err)
+				cdnFailed(&result, u.Hostname(), err)
 			}
 		}
-		result.FaviconHash = favicon.FaviconHash(result.FullUrl, result.Body)
-		result.FingerPrint = r.getFingerprintAsync(result.FullUrl, result.RawBody, result.Raw, result.RawHeader, []byte(result.FaviconHash), int32(result.StatusCode), result.Headers)
+		result.FaviconHash = r.favicon.FaviconHash(ctx, result.FullUrl, result.Body)
+		r.getFingerprintAsync(ctx, &result)
 		return result, nil
 	}
 
 	if strings.HasPrefix(host, HTTP_PREFIX) {
-		result, err = retryhttpclient.Get(host)
+		result, err = r.client.ProbeContext(ctx, host)
 		if err != nil {
 			return result, err
 		}
@@ -340,15 +617,16 @@ func (r *Runner) ScanHost(host string) (result.HostResult, error) {
 		u, err := url.Parse(host)
 		if err == nil {
 			result.Host = u.Hostname()
-			if ip, cdn, err := r.GetDomainIPWithCDN(u.Hostname()); err == nil {
+			result.Port = urlPort(u, 80)
+			if ip, cdn, err := r.GetDomainIPWithCDNContext(ctx, u.Hostname()); err == nil {
 				result.IP = ip
 				result.Cdn = cdn
 			} else {
-				gologger.Warning().Msgf("Failed to get CDN info for %s: %v", u.Hostname(), err)
+				cdnFailed(&result, u.Hostname(), err)
 			}
 		}
-		result.FaviconHash = favicon.FaviconHash(result.FullUrl, result.Body)
-		result.FingerPrint = r.getFingerprintAsync(result.FullUrl, result.RawBody, result.Raw, result.RawHeader, []byte(result.FaviconHash), int32(result.StatusCode), result.Headers)
+		result.FaviconHash = r.favicon.FaviconHash(ctx, result.FullUrl, result.Body)
+		r.getFingerprintAsync(ctx, &result)
 		return result, nil
 	}
 
@@ -361,7 +639,7 @@ func (r *Runner) ScanHost(host string) (result.HostResult, error) {
 
 	switch {
 	case parsePort == "80":
-		result, err = retryhttpclient.Get(HTTP_PREFIX + host)
+		result, err = r.client.ProbeContext(ctx, HTTP_PREFIX+host)
 		if err != nil {
 			return result, err
 		}
@@ -369,96 +647,123 @@ func (r *Runner) ScanHost(host string) (result.HostResult, error) {
 		result.TLS = false
 		result.Host = parseHost
 		result.IP = iputil.GetDomainIP(parseHost)
-		result.FaviconHash = favicon.FaviconHash(result.FullUrl, result.Body)
-		result.FingerPrint = r.getFingerprintAsync(result.FullUrl, result.RawBody, result.Raw, result.RawHeader, []byte(result.FaviconHash), int32(result.StatusCode), result.Headers)
+		result.FaviconHash = r.favicon.FaviconHash(ctx, result.FullUrl, result.Body)
+		r.getFingerprintAsync(ctx, &result)
 		return result, nil
 
 	case parsePort == "443":
-		result, err = retryhttpclient.Get(HTTPS_PREFIX + host)
+		result, err = r.client.ProbeContext(ctx, HTTPS_PREFIX+host)
 		if err != nil {
 			return result, err
 		}
 		result.Port = 443
 		result.TLS = true
 		result.Host = parseHost
-		if ip, cdn, err := r.GetDomainIPWithCDN(u.Hostname()); err == nil {
+		if ip, cdn, err := r.GetDomainIPWithCDNContext(ctx, u.Hostname()); err == nil {
 			result.IP = ip
 			result.Cdn = cdn
 		} else {
-			gologger.Warning().Msgf("Failed to get CDN info for %s: %v", u.Hostname(), err)
+			cdnFailed(&result, u.Hostname(), err)
 		}
-		result.FaviconHash = favicon.FaviconHash(result.FullUrl, result.Body)
-		result.FingerPrint = r.getFingerprintAsync(result.FullUrl, result.RawBody, result.Raw, result.RawHeader, []byte(result.FaviconHash), int32(result.StatusCode), result.Headers)
+		result.FaviconHash = r.favicon.FaviconHash(ctx, result.FullUrl, result.Body)
+		r.getFingerprintAsync(ctx, &result)
 		return result, nil
 
 	default:
-		result, err = retryhttpclient.Get(HTTPS_PREFIX + host)
+		result, err = r.client.ProbeContext(ctx, HTTPS_PREFIX+host)
+		httpsErr := err
 		if err == nil {
 			result.Port = 443
-			strPort := ""
 			if intPort, err := strconv.Atoi(parsePort); err == nil {
 				result.Port = intPort
-				strPort = ":" + parsePort
 			}
 			result.Host = parseHost
-			if ip, cdn, err := r.GetDomainIPWithCDN(u.Hostname()); err == nil {
+			if ip, cdn, err := r.GetDomainIPWithCDNContext(ctx, u.Hostname()); err == nil {
 				result.IP = ip
 				result.Cdn = cdn
 			} else {
-				gologger.Warning().Msgf("Failed to get CDN info for %s: %v", u.Hostname(), err)
+				cdnFailed(&result, u.Hostname(), err)
 			}
 			result.TLS = true
-			result.FullUrl = HTTPS_PREFIX + parseHost + strPort
-			result.FaviconHash = favicon.FaviconHash(result.FullUrl, result.Body)
-			result.FingerPrint = r.getFingerprintAsync(result.FullUrl, result.RawBody, result.Raw, result.RawHeader, []byte(result.FaviconHash), int32(result.StatusCode), result.Headers)
+			result.FullUrl = HTTPS_PREFIX + joinHostPort(parseHost, parsePort)
+			result.FaviconHash = r.favicon.FaviconHash(ctx, result.FullUrl, result.Body)
+			r.getFingerprintAsync(ctx, &result)
 			return result, err
 		}
 
-		result, err = retryhttpclient.Get(HTTP_PREFIX + host)
+		result, err = r.client.ProbeContext(ctx, HTTP_PREFIX+host)
 		if err == nil {
 			if strings.Contains(result.Body, "<title>400 The plain HTTP request was sent to HTTPS port</title>") {
 				result.Port = 443
-				strPort := ""
 				if intPort, err := strconv.Atoi(parsePort); err == nil {
 					result.Port = intPort
-					strPort = ":" + parsePort
 				}
 				result.Host = parseHost
-				if ip, cdn, err := r.GetDomainIPWithCDN(u.Hostname()); err == nil {
+				if ip, cdn, err := r.GetDomainIPWithCDNContext(ctx, u.Hostname()); err == nil {
 					result.IP = ip
 					result.Cdn = cdn
 				} else {
-					gologger.Warning().Msgf("Failed to get CDN info for %s: %v", u.Hostname(), err)
+					cdnFailed(&result, u.Hostname(), err)
 				}
 				result.TLS = true
-				result.FullUrl = HTTPS_PREFIX + parseHost + strPort
-				result.FaviconHash = favicon.FaviconHash(result.FullUrl, result.Body)
-				result.FingerPrint = r.getFingerprintAsync(result.FullUrl, result.RawBody, result.Raw, result.RawHeader, []byte(result.FaviconHash), int32(result.StatusCode), result.Headers)
+				result.FullUrl = HTTPS_PREFIX + joinHostPort(parseHost, parsePort)
+				result.FaviconHash = r.favicon.FaviconHash(ctx, result.FullUrl, result.Body)
+				r.getFingerprintAsync(ctx, &result)
 				return result, nil
 			}
 			result.Port = 80
-			strPort := ""
 			if intPort, err := strconv.Atoi(parsePort); err == nil {
 				result.Port = intPort
-				strPort = ":" + parsePort
 			}
 			result.Host = parseHost
 			result.TLS = false
-			if ip, cdn, err := r.GetDomainIPWithCDN(u.Hostname()); err == nil {
+			if ip, cdn, err := r.GetDomainIPWithCDNContext(ctx, u.Hostname()); err == nil {
 				result.IP = ip
 				result.Cdn = cdn
 			} else {
-				gologger.Warning().Msgf("Failed to get CDN info for %s: %v", u.Hostname(), err)
+				cdnFailed(&result, u.Hostname(), err)
 			}
-			result.FullUrl = HTTP_PREFIX + parseHost + strPort
-			result.FaviconHash = favicon.FaviconHash(result.FullUrl, result.Body)
-			result.FingerPrint = r.getFingerprintAsync(result.FullUrl, result.RawBody, result.Raw, result.RawHeader, []byte(result.FaviconHash), int32(result.StatusCode), result.Headers)
+			result.FullUrl = HTTP_PREFIX + joinHostPort(parseHost, parsePort)
+			result.FaviconHash = r.favicon.FaviconHash(ctx, result.FullUrl, result.Body)
+			r.getFingerprintAsync(ctx, &result)
 			return result, nil
 		}
 
+		return result, scanFailed(httpsErr, err)
 	}
+}
+
+// scanFailed 合并 HTTPS 和 HTTP 两次探测的错误，端口不是 TLS 服务时以 HTTP 的错误为准
+func scanFailed(httpsErr, httpErr error) error {
+	if result.IsNotTLS(httpsErr) {
+		return fmt.Errorf("scan host failed: %w", httpErr)
+	}
+	return fmt.Errorf("scan host failed: %w", httpsErr)
+}
+
+// cdnFailed 记录 CDN 检测失败，超时记录到 Timeouts
+func cdnFailed(rst *result.HostResult, host string, err error) {
+	gologger.Warning().Msgf("Failed to get CDN info for %s: %v", host, err)
+	rst.AddError(result.StageCdn, err)
+}
+
+// joinHostPort 拼接 URL 中的主机和端口，IPv6 地址加上方括号，port 为空时只返回主机
+func joinHostPort(host, port string) string {
+	if len(port) > 0 {
+		return net.JoinHostPort(host, port)
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
 
-	return result, fmt.Errorf("scan host failed")
+// urlPort 返回 URL 中的端口，未指定时返回协议默认端口
+func urlPort(u *url.URL, defaultPort int) int {
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		return port
+	}
+	return defaultPort
 }
 
 func getFingerprint(target string, body, raw, rawheader, faviconhash []byte, status int32, headers map[string]string) string {
@@ -483,6 +788,9 @@ func fingerprintSlice2String(f []string) string {
 	fingerprint := ""
 	if len(f) > 0 {
 		for _, f := range f {
+			if len(f) == 0 {
+				continue
+			}
 			fingerprint += "," + f
 		}
 		fingerprint = strings.TrimLeft(fingerprint, ",")
@@ -490,9 +798,14 @@ func fingerprintSlice2String(f []string) string {
 	return fingerprint
 }
 
+// Close 释放扫描使用的资源，同时停止投递，使 bindContext 的协程退出
 func (r *Runner) Close() error {
-	if r.ticker != nil {
-		r.ticker.Stop()
+	r.Stop()
+	if r.limiter != nil {
+		r.limiter.Stop()
+	}
+	if r.stream != nil {
+		r.stream.Close()
 	}
 	return os.RemoveAll(r.hostTempFile)
 }
@@ -505,6 +818,11 @@ func (r *Runner) Close() error {
 //	cdn 域名的CDN信息
 //	err 错误
 func (r *Runner) GetDomainIPWithCDN(domain string) (string, string, error) {
+	return r.GetDomainIPWithCDNContext(context.Background(), domain)
+}
+
+// GetDomainIPWithCDNContext 与 GetDomainIPWithCDN 相同，ctx 取消时中止解析
+func (r *Runner) GetDomainIPWithCDNContext(ctx context.Context, domain string) (string, string, error) {
 	if domain == "" {
 		return "", "", fmt.Errorf("域名不能为空")
 	}
@@ -521,16 +839,20 @@ func (r *Runner) GetDomainIPWithCDN(domain string) (string, string, error) {
 	}
 
 	// 处理IP输入
+	// IP 只在本地匹配 CDN 网段，不经过代理
+	cdnchecker, proxy := r.cdnChecker()
 	if iputil.IsIP(domain) {
-		result, _ := r.cdnchecker.CheckIP(domain)
+		result, _ := cdnchecker.CheckIP(domain)
 		return domain, formatCDNInfo(result.IsCDN, result.Provider), nil
 	}
 
 	// 处理域名输入
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(defaultCdncheckTimeout)*time.Second)
+	checkCtx, cancel := context.WithTimeout(ctx, time.Duration(r.Options.CdnTimeout)*time.Second)
 	defer cancel()
 
-	result, err := r.cdnchecker.CheckDomain(ctx, domain)
+	result, err := cdnchecker.CheckDomain(checkCtx, domain)
+	// 代理不可用时检测会用完 -cdn-timeout，确认代理使用目标的 ctx
+	r.markCDNProxy(ctx, proxy, result)
 	if err != nil {
 		return "", "", err
 	}
@@ -545,10 +867,14 @@ func (r *Runner) GetDomainIPWithCDN(domain string) (string, string, error) {
 	return strings.Join(result.IPs, ","), formatCDNInfo(result.IsCDN, result.Provider), nil
 }
 
-// 新增：异步指纹识别函数
-func (r *Runner) getFingerprintAsync(target string, body, raw, rawheader, faviconhash []byte, status int32, headers map[string]string) string {
+// 新增：异步指纹识别函数，超过 -fingerprint-timeout 时记录到 Timeouts
+func (r *Runner) getFingerprintAsync(ctx context.Context, rst *result.HostResult) {
 	resultChan := make(chan string, 1)
 
+	// 超时后调用方会继续处理结果，识别协程只使用这里复制出的字段
+	target, body, raw, rawheader := rst.FullUrl, rst.RawBody, rst.Raw, rst.RawHeader
+	faviconhash, status, headers := []byte(rst.FaviconHash), int32(rst.StatusCode), rst.Headers
+
 	go func() {
 		// 获取信号量，限制并发数
 		r.fingerprintSemaphore <- struct{}{}
@@ -562,9 +888,10 @@ func (r *Runner) getFingerprintAsync(target string, body, raw, rawheader, favico
 	// 设置超时，避免长时间阻塞
 	select {
 	case fingerprint := <-resultChan:
-		return fingerprint
-	case <-time.After(5 * time.Second):
-		return "" // 超时返回空字符串
+		rst.FingerPrint = fingerprint
+	case <-time.After(time.Duration(r.Options.FingerprintTimeout) * time.Second):
+		rst.AddTimeout(result.StageFingerprint)
+	case <-ctx.Done():
 	}
 }
 
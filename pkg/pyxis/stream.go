@@ -0,0 +1,184 @@
+package pyxis
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zan8in/pyxis/pkg/result"
+	"github.com/zan8in/pyxis/pkg/util/fileutil"
+)
+
+// streamWriter 在结果到达时逐条写入并刷新，进程被中断也不会丢失已完成的结果
+type streamWriter struct {
+	sync.Mutex
+	file     *os.File
+	buf      *bufio.Writer
+	csv      *csv.Writer
+	output   string
+	fileType fileutil.FileType
+	count    int
+}
+
+// openStream 创建流式写入器，.jsonl 始终流式写入，-stream 模式下所有格式都流式写入
+func (r *Runner) openStream() error {
+	output := r.Options.Output
+	if len(output) == 0 {
+		return nil
+	}
+
+	// 断点续扫需要中断时已完成的结果已经落盘，因此也使用流式写入
+	fileType := fileutil.FileExt(output)
+	if fileType != fileutil.FILE_JSONL && !r.Options.Stream && r.checkpoint == nil {
+		return nil
+	}
+
+	outputFolder := filepath.Dir(output)
+	if !fileutil.FolderExists(outputFolder) {
+		if err := os.MkdirAll(outputFolder, 0700); err != nil {
+			return fmt.Errorf("could not create output folder %s: %s", outputFolder, err)
+		}
+	}
+
+	if r.checkpoint != nil && r.checkpoint.resumed && fileutil.FileExists(output) {
+		return r.appendStream(output, fileType)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("could not create file %s: %s", output, err)
+	}
+
+	w := &streamWriter{
+		file:     file,
+		buf:      bufio.NewWriter(file),
+		output:   output,
+		fileType: fileType,
+	}
+
+	switch fileType {
+	case fileutil.FILE_CSV:
+		w.buf.WriteString("\xEF\xBB\xBF")
+		w.csv = csv.NewWriter(w.buf)
+		w.csv.Write(csvHeader())
+		w.csv.Flush()
+	case fileutil.FILE_JSON:
+		w.buf.WriteString("[")
+	}
+
+	r.stream = w
+	return w.buf.Flush()
+}
+
+// appendStream 续扫时在已有输出后追加，JSON 数组去掉结尾的 ] 后继续写入
+func (r *Runner) appendStream(output string, fileType fileutil.FileType) error {
+	file, err := os.OpenFile(output, os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open file %s: %s", output, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w := &streamWriter{
+		file:     file,
+		output:   output,
+		fileType: fileType,
+	}
+
+	size := info.Size()
+	if fileType == fileutil.FILE_JSON {
+		data, err := os.ReadFile(output)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		content := strings.TrimRight(string(data), " \r\n\t")
+		content = strings.TrimSuffix(content, "]")
+		size = int64(len(content))
+		if strings.TrimSpace(strings.TrimPrefix(content, "\xEF\xBB\xBF")) != "[" {
+			w.count = 1
+		}
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+
+	w.buf = bufio.NewWriter(file)
+	if fileType == fileutil.FILE_CSV {
+		w.csv = csv.NewWriter(w.buf)
+		// 中断前还没有写入表头时补上
+		if size == 0 {
+			w.buf.WriteString("\xEF\xBB\xBF")
+			w.csv.Write(csvHeader())
+			w.csv.Flush()
+		}
+	}
+
+	r.stream = w
+	return nil
+}
+
+func (w *streamWriter) Write(result *result.HostResult) error {
+	or := NewOutputResult(result)
+
+	w.Lock()
+	defer w.Unlock()
+
+	switch w.fileType {
+	case fileutil.FILE_JSON, fileutil.FILE_JSONL:
+		b, err := or.JSON()
+		if err != nil {
+			return err
+		}
+		if w.fileType == fileutil.FILE_JSONL {
+			b = append(b, '\n')
+		} else if w.count > 0 {
+			w.buf.WriteString(",")
+		}
+		if _, err := w.buf.Write(b); err != nil {
+			return err
+		}
+	case fileutil.FILE_CSV:
+		if err := w.csv.Write(or.CSV()); err != nil {
+			return err
+		}
+		w.csv.Flush()
+	default:
+		if _, err := w.buf.WriteString(or.TXT()); err != nil {
+			return err
+		}
+	}
+	w.count++
+
+	return w.buf.Flush()
+}
+
+func (w *streamWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.fileType == fileutil.FILE_JSON {
+		w.buf.WriteString("]")
+	}
+
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
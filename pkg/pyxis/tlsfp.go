@@ -0,0 +1,79 @@
+package pyxis
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zan8in/pyxis/pkg/result"
+	"github.com/zan8in/pyxis/pkg/tlsfp"
+	"github.com/zan8in/pyxis/pkg/util/iputil"
+)
+
+// loadTLSFingerprintDB 加载 TLS 指纹库，每行格式为 "指纹 名称"，# 开头为注释
+func loadTLSFingerprintDB(fileName string) (map[string]string, error) {
+	db := make(map[string]string)
+	if len(fileName) == 0 {
+		return db, nil
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(c rune) bool {
+			return c == ',' || c == ' ' || c == '\t'
+		})
+		if len(fields) < 2 {
+			continue
+		}
+		db[strings.ToLower(fields[0])] = strings.Join(fields[1:], " ")
+	}
+
+	return db, s.Err()
+}
+
+// tlsFingerprint 对 HTTPS 站点计算 TLS 服务端指纹，并作为指纹识别的输入
+func (r *Runner) tlsFingerprint(ctx context.Context, rst *result.HostResult) {
+	// 指纹探测直连目标，配置了代理时跳过，避免绕过代理暴露真实地址
+	if !r.Options.TLSFingerprint || !rst.TLS || r.proxies != nil {
+		return
+	}
+
+	serverName := rst.Host
+	if iputil.IsIP(serverName) {
+		serverName = ""
+	}
+
+	address := net.JoinHostPort(rst.Host, strconv.Itoa(rst.Port))
+	rst.TLSFingerprint = tlsfp.Fingerprint(ctx, address, serverName, time.Duration(r.Options.Timeout)*time.Second)
+
+	r.matchTLSFingerprint(rst)
+}
+
+// matchTLSFingerprint 将指纹库中命中的名称合并到 FingerPrint
+func (r *Runner) matchTLSFingerprint(rst *result.HostResult) {
+	if len(rst.TLSFingerprint) == 0 || rst.TLSFingerprint == tlsfp.Empty {
+		return
+	}
+
+	if name, ok := r.tlsfpDB[rst.TLSFingerprint]; ok {
+		addFingerprint(rst, result.Fingerprint{
+			Name:     name,
+			Evidence: "tlsfp: " + rst.TLSFingerprint,
+			RuleID:   tlsfpRuleID,
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package pyxis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zan8in/pyxis/pkg/http/retryhttpclient"
+	"github.com/zan8in/pyxis/pkg/result"
+	"github.com/zan8in/pyxis/pkg/util/iputil"
+)
+
+// vhostLengthTolerance 响应体哈希不同但长度相差在 5% 以内时视为同一页面，避免动态内容造成误报
+const vhostLengthTolerance = 0.05
+
+// loadVhosts 读取 -vhost-file 中的候选主机名，每行一个
+func loadVhosts(options *Options) ([]string, error) {
+	if len(options.VhostFile) == 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(options.VhostFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vhosts []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if vhost := strings.TrimSpace(s.Text()); len(vhost) > 0 && !strings.HasPrefix(vhost, "#") {
+			vhosts = append(vhosts, vhost)
+		}
+	}
+	return vhosts, s.Err()
+}
+
+// vhostCandidates 合并 -vhost-file、证书 SAN 和 IP 的 PTR 记录，去重并跳过通配符和 IP
+func (r *Runner) vhostCandidates(ctx context.Context, origin *result.HostResult) []string {
+	var (
+		candidates []string
+		seen       = map[string]struct{}{origin.Host: {}}
+	)
+
+	add := func(vhost string) {
+		vhost = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(vhost)), ".")
+		if len(vhost) == 0 || strings.HasPrefix(vhost, "*") || iputil.IsIP(vhost) {
+			return
+		}
+		if _, ok := seen[vhost]; ok {
+			return
+		}
+		seen[vhost] = struct{}{}
+		candidates = append(candidates, vhost)
+	}
+
+	for _, vhost := range r.vhosts {
+		add(vhost)
+	}
+	if origin.TLSInfo != nil {
+		for _, san := range origin.TLSInfo.SANs {
+			add(san)
+		}
+	}
+	if names, err := net.DefaultResolver.LookupAddr(ctx, origin.Host); err == nil {
+		for _, name := range names {
+			add(name)
+		}
+	}
+
+	return candidates
+}
+
+// ScanVhost 连接源站的 IP 和端口，以 vhost 作为 Host 和 SNI 请求，结果的 Host 为 vhost
+func (r *Runner) ScanVhost(ctx context.Context, origin *result.HostResult, vhost string) (result.HostResult, error) {
+	var rst result.HostResult
+
+	u, err := url.Parse(origin.FullUrl)
+	if err != nil {
+		return rst, err
+	}
+
+	target := u.Scheme + "://" + vhost
+	if len(u.Port()) > 0 {
+		target = u.Scheme + "://" + net.JoinHostPort(vhost, u.Port())
+	}
+
+	// favicon 请求同样连接源站的 IP
+	ctx, err = retryhttpclient.WithDialAddr(ctx, target, net.JoinHostPort(origin.Host, strconv.Itoa(origin.Port)))
+	if err != nil {
+		return rst, err
+	}
+
+	rst, err = r.client.ProbeContext(ctx, target)
+	if err != nil {
+		return rst, err
+	}
+
+	rst.Host = vhost
+	rst.IP = origin.Host
+	rst.Vhost = true
+	r.enrich(ctx, &rst, origin)
+
+	return rst, nil
+}
+
+// scanVhosts 对裸 IP 目标请求候选主机名，响应与默认虚拟主机不同时单独输出结果。
+// 除了直接访问 IP 的响应，还以一个不存在的主机名的响应作为基线，配置代理时跳过。
+// 未完成的候选记录在源站结果的 Errors 中
+func (r *Runner) scanVhosts(origin *result.HostResult) {
+	if !r.Options.Vhost || r.proxies != nil || !iputil.IsIP(origin.Host) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, time.Duration(r.Options.TargetTimeout)*time.Second)
+	defer cancel()
+
+	candidates := r.vhostCandidates(ctx, origin)
+	if len(candidates) == 0 {
+		return
+	}
+
+	baselines := []*result.HostResult{origin}
+	if rst, err := r.ScanVhost(ctx, origin, fmt.Sprintf("pyxis-%d.invalid", rand.Uint32())); err == nil {
+		baselines = append(baselines, &rst)
+	}
+
+	r.subscan(origin, result.StageVhost, candidates, func(ctx context.Context, vhost string) error {
+		rst, err := r.ScanVhost(ctx, origin, vhost)
+		if err != nil {
+			return err
+		}
+		if !matchesAny(&rst, baselines) {
+			r.sendResult(&rst)
+		}
+		return nil
+	})
+}
+
+func matchesAny(rst *result.HostResult, baselines []*result.HostResult) bool {
+	for _, baseline := range baselines {
+		if sameResponse(rst, baseline) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameResponse 判断两个响应是否为同一个页面：状态码和标题相同，且响应体哈希相同或长度相近
+func sameResponse(a, b *result.HostResult) bool {
+	if a.StatusCode != b.StatusCode || a.Title != b.Title {
+		return false
+	}
+	if a.SHA256() == b.SHA256() {
+		return true
+	}
+	diff := a.ContentLength - b.ContentLength
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) <= float64(max(a.ContentLength, b.ContentLength))*vhostLengthTolerance
+}
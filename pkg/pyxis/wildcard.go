@@ -0,0 +1,119 @@
+package pyxis
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zan8in/gologger"
+	"github.com/zan8in/pyxis/pkg/result"
+	"github.com/zan8in/pyxis/pkg/util/hashutil"
+	"github.com/zan8in/pyxis/pkg/util/iputil"
+	"golang.org/x/net/publicsuffix"
+)
+
+// wildcardBaseline 是父域名下随机子域名在同一协议和端口上的响应，子域名无法访问时 rst 为 nil。
+// done 关闭后 rst 可读
+type wildcardBaseline struct {
+	once sync.Once
+	done chan struct{}
+	rst  *result.HostResult
+}
+
+// parentDomain 返回去掉第一级后的父域名，IP、可注册域名本身和公共后缀返回空，
+// 例如 a.example.co.uk 返回 example.co.uk，example.co.uk 返回空
+func parentDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if iputil.IsIP(host) {
+		return ""
+	}
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil || host == registrable {
+		return ""
+	}
+	_, parent, _ := strings.Cut(host, ".")
+	return parent
+}
+
+// wildcardBaseline 返回 rst 所在父域名的基线，每个父域名、协议和端口只探测一次，
+// 并发的目标等待同一次探测，ctx 结束时不再等待并返回 nil
+func (r *Runner) wildcardBaseline(ctx context.Context, rst *result.HostResult) *result.HostResult {
+	parent := parentDomain(rst.Host)
+	if len(parent) == 0 {
+		return nil
+	}
+
+	u, err := url.Parse(rst.FullUrl)
+	if err != nil {
+		return nil
+	}
+
+	key := u.Scheme + "://*." + parent + ":" + u.Port()
+	v, _ := r.wildcards.LoadOrStore(key, &wildcardBaseline{done: make(chan struct{})})
+	baseline := v.(*wildcardBaseline)
+	baseline.once.Do(func() {
+		go r.probeWildcard(baseline, key, u, parent)
+	})
+
+	select {
+	case <-baseline.done:
+		return baseline.rst
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// probeWildcard 请求父域名下的随机子域名。不使用触发探测的目标的 ctx，
+// 它超时会让同一父域名的其他目标都没有基线
+func (r *Runner) probeWildcard(baseline *wildcardBaseline, key string, u *url.URL, parent string) {
+	defer close(baseline.done)
+
+	ctx, cancel := context.WithTimeout(r.ctx, time.Duration(r.Options.TargetTimeout)*time.Second)
+	defer cancel()
+
+	probe := fmt.Sprintf("pyxis-%d.%s", rand.Uint32(), parent)
+	target := u.Scheme + "://" + probe
+	if len(u.Port()) > 0 {
+		target = u.Scheme + "://" + net.JoinHostPort(probe, u.Port())
+	}
+
+	b, err := r.client.ProbeContext(ctx, target)
+	if err != nil {
+		return
+	}
+	b.Host = probe
+	baseline.rst = normalizeWildcard(&b)
+	gologger.Debug().Msgf("%s responds to random subdomains: %d %q", key, b.StatusCode, b.Title)
+}
+
+// normalizeWildcard 将标题和响应体中的主机名替换为占位符，泛解析页面常常包含访问的域名，
+// 替换后不同长度的子域名才能与基线比较。返回的结果只保留比较需要的字段
+func normalizeWildcard(rst *result.HostResult) *result.HostResult {
+	body := strings.ReplaceAll(rst.Body, rst.Host, "{{host}}")
+	return &result.HostResult{
+		StatusCode:    rst.StatusCode,
+		Title:         strings.ReplaceAll(rst.Title, rst.Host, "{{host}}"),
+		ContentLength: int64(len(body)),
+		BodySHA256:    hashutil.SHA256([]byte(body)),
+	}
+}
+
+// dropWildcard 标记与父域名随机子域名响应相同的结果，未指定 -keep-wildcard 时返回 true 表示丢弃
+func (r *Runner) dropWildcard(ctx context.Context, rst *result.HostResult) bool {
+	if !r.Options.Wildcard {
+		return false
+	}
+
+	baseline := r.wildcardBaseline(ctx, rst)
+	if baseline == nil || !sameResponse(normalizeWildcard(rst), baseline) {
+		return false
+	}
+
+	rst.Wildcard = true
+	return !r.Options.KeepWildcard
+}
@@ -0,0 +1,133 @@
+package result
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// Failure reasons of failed results, they tell dead hosts from filtered ones
+// and from hosts with broken TLS
+const (
+	FailureDNS     = "dns_nxdomain"       // the domain does not resolve
+	FailureRefused = "connection_refused" // the port is closed or the host is unreachable
+	FailureTimeout = "timeout"            // no answer in time, usually filtered
+	FailureTLS     = "tls_error"          // the tls handshake failed
+	FailureProxy   = "proxy_error"        // the proxy could not be reached or refused to connect
+	FailureHTTP    = "http_error"         // the server did not answer with valid http
+	FailureUnknown = "unknown"
+)
+
+// Fail marks the result as failed and records the reason and the error
+func (hr *HostResult) Fail(err error) {
+	hr.Flag = Failed
+	hr.Failure = Classify(err)
+	if err != nil {
+		hr.AddError(StageTarget, err)
+	}
+}
+
+// Classify returns the failure reason of a request error
+func Classify(err error) string {
+	if err == nil {
+		return FailureUnknown
+	}
+
+	// 代理错误优先判断，代理拒绝连接不代表目标端口关闭
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		switch {
+		case opErr.Op == "proxyconnect":
+			return FailureProxy
+		case strings.HasPrefix(opErr.Op, "socks"):
+			return classifySocks(opErr.Err)
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return FailureTimeout
+		}
+		return FailureDNS
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
+		return FailureRefused
+	case isTimeout(err):
+		return FailureTimeout
+	}
+
+	var (
+		recordErr tls.RecordHeaderError
+		alertErr  tls.AlertError
+		certErr   *tls.CertificateVerificationError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &certErr) ||
+		IsNotTLS(err) || strings.Contains(err.Error(), "tls: ") {
+		return FailureTLS
+	}
+
+	// 连接在返回有效响应前被关闭或重置
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		strings.Contains(err.Error(), "malformed HTTP") || strings.Contains(err.Error(), "net/http: ") {
+		return FailureHTTP
+	}
+
+	return FailureUnknown
+}
+
+// socksReplyPrefix prefixes the reply of a SOCKS server that failed to connect the target
+const socksReplyPrefix = "unknown error "
+
+// classifySocks tells target errors forwarded by a working SOCKS proxy from failures of the proxy itself.
+// net/http reports both as a "socks connect" OpError, the reply codes about the target are not proxy failures
+func classifySocks(err error) string {
+	if err == nil {
+		return FailureProxy
+	}
+	if reply, ok := strings.CutPrefix(err.Error(), socksReplyPrefix); ok {
+		switch reply {
+		case "connection refused", "host unreachable", "network unreachable":
+			return FailureRefused
+		case "TTL expired":
+			return FailureTimeout
+		case "general SOCKS server failure":
+			// 多数代理在目标无法解析或无法连接时返回该错误，不能说明代理不可用
+			return FailureUnknown
+		}
+		return FailureProxy
+	}
+	// 等待代理回复时超时，通常是代理在连接被过滤的目标
+	if isTimeout(err) {
+		return FailureTimeout
+	}
+	// 握手、认证失败或代理断开连接
+	return FailureProxy
+}
+
+// IsNotTLS reports whether the error means the server does not speak tls,
+// net/http replaces the record header error when the server answered plain http
+func IsNotTLS(err error) bool {
+	var recordErr tls.RecordHeaderError
+	return errors.As(err, &recordErr) || (err != nil && strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"))
+}
+
+// Key returns the key of the result, failed results have no url and use the host.
+// Vhost results add the IP they were found on, the same name may also be scanned
+// directly or served by other IPs
+func (hr *HostResult) Key() string {
+	if hr.Flag != 0 || len(hr.FullUrl) == 0 {
+		return hr.Host
+	}
+	if hr.Vhost {
+		return hr.FullUrl + "@" + hr.IP
+	}
+	return hr.FullUrl
+}
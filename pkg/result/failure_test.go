@@ -0,0 +1,87 @@
+package result
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	socks := func(err error) error {
+		return &net.OpError{Op: "socks connect", Net: "tcp", Err: err}
+	}
+	// net/http 返回的错误都包在 url.Error 中
+	get := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.com", Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, FailureUnknown},
+		{"nxdomain", get(&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}), FailureDNS},
+		{"dns timeout", get(&net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}), FailureTimeout},
+		{"refused", get(refused), FailureRefused},
+		{"host unreachable", get(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}), FailureRefused},
+		{"deadline", get(context.DeadlineExceeded), FailureTimeout},
+		{"wrapped deadline", fmt.Errorf("scan host failed: %w", get(context.DeadlineExceeded)), FailureTimeout},
+		{"tls record header", get(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), FailureTLS},
+		{"http response to https client", get(errors.New("http: server gave HTTP response to HTTPS client")), FailureTLS},
+		{"tls alert", get(tls.AlertError(40)), FailureTLS},
+		{"eof", get(io.EOF), FailureHTTP},
+		{"reset", get(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), FailureHTTP},
+		{"malformed", get(errors.New("net/http: HTTP/1.x transport connection broken: malformed HTTP response")), FailureHTTP},
+		{"unknown", errors.New("something else"), FailureUnknown},
+
+		// 代理本身的错误
+		{"proxyconnect refused", get(&net.OpError{Op: "proxyconnect", Net: "tcp", Err: refused}), FailureProxy},
+		{"socks auth", get(socks(errors.New("username/password authentication failed"))), FailureProxy},
+		{"socks no auth method", get(socks(errors.New("no acceptable authentication methods"))), FailureProxy},
+		{"socks eof", get(socks(io.ErrUnexpectedEOF)), FailureProxy},
+		{"socks ruleset", get(socks(errors.New("unknown error connection not allowed by ruleset"))), FailureProxy},
+
+		// SOCKS 代理转发的目标错误
+		{"socks refused", get(socks(errors.New("unknown error connection refused"))), FailureRefused},
+		{"socks host unreachable", get(socks(errors.New("unknown error host unreachable"))), FailureRefused},
+		{"socks network unreachable", get(socks(errors.New("unknown error network unreachable"))), FailureRefused},
+		{"socks ttl expired", get(socks(errors.New("unknown error TTL expired"))), FailureTimeout},
+		{"socks general failure", get(socks(errors.New("unknown error general SOCKS server failure"))), FailureUnknown},
+		{"socks reply timeout", get(socks(context.DeadlineExceeded)), FailureTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	direct := &HostResult{Host: "www.foo.test", FullUrl: "https://www.foo.test:443"}
+	vhostA := &HostResult{Host: "www.foo.test", IP: "10.0.0.1", FullUrl: "https://www.foo.test:443", Vhost: true}
+	vhostB := &HostResult{Host: "www.foo.test", IP: "10.0.0.2", FullUrl: "https://www.foo.test:443", Vhost: true}
+	failed := &HostResult{Host: "www.foo.test", Flag: 1}
+
+	keys := map[string]struct{}{}
+	for _, hr := range []*HostResult{direct, vhostA, vhostB} {
+		keys[hr.Key()] = struct{}{}
+	}
+	if len(keys) != 3 {
+		t.Errorf("vhost results share keys: %v", keys)
+	}
+	if got := failed.Key(); got != "www.foo.test" {
+		t.Errorf("failed result key = %q, want the host", got)
+	}
+}
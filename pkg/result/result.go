@@ -1,7 +1,16 @@
 package result
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/zan8in/pyxis/pkg/util/hashutil"
 )
 
 const (
@@ -14,14 +23,18 @@ const (
 type Result struct {
 	sync.RWMutex
 	hosts map[string]*HostResult
+	seen  map[string]struct{}
 }
 
 type HostResult struct {
 	Flag          int    //
 	FullUrl       string // The full URL
+	FinalUrl      string // URL of the final response after redirects
+	Redirects     []Redirect
 	Host          string // example.com or ip addr
 	Port          int    // port number
 	TLS           bool   // true if TLS
+	TLSInfo       *TLSInfo
 	IP            string // IP address
 	Title         string // title of the response
 	Body          string // body of the response
@@ -35,11 +48,85 @@ type HostResult struct {
 	Raw           []byte // raw
 	RawHeader     []byte // header
 	Headers       map[string]string
+
+	BodyMD5       string // md5 of the response body after utf-8 conversion
+	BodySHA256    string // sha256 of the response body after utf-8 conversion
+	HeaderSetHash string // md5 of the sorted lowercase response header names, net/http does not keep their order
+	Simhash       string // 64-bit simhash of the body text in hex, near-identical pages differ in a few bits
+
+	TLSFingerprint string // tls server fingerprint
+
+	Fingerprints []Fingerprint // structured fingerprints, FingerPrint joins their names
+
+	Timeouts []string // stages that ran out of time: target, fingerprint, cdn
+	Errors   []string // "stage: error" of the stages that failed
+	Failure  string   // failure reason of failed results, one of the Failure consts
+
+	Vhost    bool // found by virtual host enumeration, IP is the address it was requested on
+	Wildcard bool // same response as a random subdomain of the parent domain
+}
+
+const (
+	StageTarget      = "target"
+	StageFingerprint = "fingerprint"
+	StageCdn         = "cdn"
+	StageVhost       = "vhost"
+	StagePath        = "path"
+)
+
+// Fingerprint is an identified product and the rule that matched it
+type Fingerprint struct {
+	Name     string `json:"name"`
+	Vendor   string `json:"vendor,omitempty"`
+	Category string `json:"category,omitempty"` // cms, waf, framework, middleware...
+	Version  string `json:"version,omitempty"`
+	Evidence string `json:"evidence,omitempty"` // the matched snippet
+	RuleID   string `json:"ruleid,omitempty"`
+}
+
+// Redirect is a single hop of the redirect chain
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statuscode"`
+	Location   string `json:"location,omitempty"`
+}
+
+// TLSInfo is the leaf certificate and handshake metadata of a https response
+type TLSInfo struct {
+	SubjectCN   string    `json:"subjectcn,omitempty"`
+	SANs        []string  `json:"sans,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	NotBefore   time.Time `json:"notbefore"`
+	NotAfter    time.Time `json:"notafter"`
+	Serial      string    `json:"serial,omitempty"`
+	SHA256      string    `json:"sha256,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	CipherSuite string    `json:"ciphersuite,omitempty"`
+	ALPN        string    `json:"alpn,omitempty"`
+}
+
+func (t *TLSInfo) String() string {
+	if t == nil {
+		return ""
+	}
+	return fmt.Sprintf("cn=%s;san=%s;issuer=%s;notbefore=%s;notafter=%s;serial=%s;sha256=%s;version=%s;cipher=%s;alpn=%s",
+		t.SubjectCN,
+		strings.Join(t.SANs, ","),
+		t.Issuer,
+		t.NotBefore.Format(time.DateOnly),
+		t.NotAfter.Format(time.DateOnly),
+		t.Serial,
+		t.SHA256,
+		t.Version,
+		t.CipherSuite,
+		t.ALPN,
+	)
 }
 
 func NewResult() *Result {
 	return &Result{
 		hosts: make(map[string]*HostResult),
+		seen:  make(map[string]struct{}),
 	}
 }
 
@@ -85,3 +172,90 @@ func (r *Result) SetHostResult(host string, hostResult *HostResult) {
 
 	r.hosts[host] = hostResult
 }
+
+// Mark records the key without keeping the host result, it returns false
+// if the key has been marked before
+func (r *Result) Mark(key string) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.seen[key]; ok {
+		return false
+	}
+	r.seen[key] = struct{}{}
+	return true
+}
+
+// AddTimeout records a stage that ran out of time, so that an empty field
+// can be told apart from a miss
+func (hr *HostResult) AddTimeout(stage string) {
+	for _, s := range hr.Timeouts {
+		if s == stage {
+			return
+		}
+	}
+	hr.Timeouts = append(hr.Timeouts, stage)
+}
+
+// AddError records a failed stage, timeouts go to Timeouts instead
+func (hr *HostResult) AddError(stage string, err error) {
+	if isTimeout(err) {
+		hr.AddTimeout(stage)
+		return
+	}
+	hr.Errors = append(hr.Errors, stage+": "+err.Error())
+}
+
+// AddItemError records a failed request of a stage that covers several items, such as the vhosts of an ip.
+// The item is kept in Errors for timeouts too, Timeouts only has stage names
+func (hr *HostResult) AddItemError(stage, item string, err error) {
+	if isTimeout(err) {
+		hr.AddTimeout(stage)
+	}
+	hr.Errors = append(hr.Errors, stage+": "+item+": "+err.Error())
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Hash fills the body and header hashes that are still empty, it must run
+// before Compact. Only results that are output are hashed, not every request
+func (hr *HostResult) Hash() {
+	if len(hr.BodyMD5) == 0 {
+		hr.BodyMD5 = hashutil.MD5([]byte(hr.Body))
+	}
+	hr.SHA256()
+	if len(hr.Simhash) == 0 {
+		hr.Simhash = hashutil.FormatSimhash(hashutil.Simhash(hr.Body))
+	}
+	if len(hr.HeaderSetHash) == 0 {
+		names := make([]string, 0, len(hr.Headers))
+		for name := range hr.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		hr.HeaderSetHash = hashutil.MD5([]byte(strings.Join(names, "\n")))
+	}
+}
+
+// SHA256 returns the sha256 of the body, computing it on first use
+func (hr *HostResult) SHA256() string {
+	if len(hr.BodySHA256) == 0 {
+		hr.BodySHA256 = hashutil.SHA256([]byte(hr.Body))
+	}
+	return hr.BodySHA256
+}
+
+// Compact drops the response body and headers once fingerprinting is done
+func (hr *HostResult) Compact() {
+	hr.Body = ""
+	hr.RawBody = nil
+	hr.Raw = nil
+	hr.RawHeader = nil
+	hr.Headers = nil
+}
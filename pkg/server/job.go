@@ -0,0 +1,296 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zan8in/pyxis/pkg/http/retryhttpclient"
+	"github.com/zan8in/pyxis/pkg/proxypool"
+	"github.com/zan8in/pyxis/pkg/pyxis"
+	"github.com/zan8in/pyxis/pkg/result"
+	"github.com/zan8in/pyxis/pkg/util/portutil"
+)
+
+const (
+	StatusQueued   = "queued"
+	StatusRunning  = "running"
+	StatusFinished = "finished"
+	StatusCanceled = "canceled"
+	StatusFailed   = "failed"
+)
+
+// JobRequest 是提交扫描任务的请求体，未填写的选项使用命令行的默认值
+type JobRequest struct {
+	Targets        []string `json:"targets"`
+	Ports          string   `json:"ports,omitempty"`
+	Paths          []string `json:"paths,omitempty"`
+	Timeout        int      `json:"timeout,omitempty"`
+	Retries        int      `json:"retries,omitempty"`
+	RateLimit      int      `json:"rate,omitempty"`
+	Proxy          string   `json:"proxy,omitempty"`
+	FollowRedirect string   `json:"followredirect,omitempty"`
+	Cdn            bool     `json:"cdn,omitempty"`
+	TLSSan         bool     `json:"tlssan,omitempty"`
+	TLSFingerprint bool     `json:"tlsfingerprint,omitempty"`
+	Headers        []string `json:"headers,omitempty"`
+	Cookie         string   `json:"cookie,omitempty"`
+	Method         string   `json:"method,omitempty"`
+	Body           string   `json:"body,omitempty"`
+	UserAgent      string   `json:"ua,omitempty"`
+}
+
+var (
+	errNoTargets = errors.New("no targets provided")
+)
+
+// options 校验请求并转换为扫描选项
+func (req *JobRequest) options() (*pyxis.Options, error) {
+	var targets []string
+	for _, target := range req.Targets {
+		if target = strings.TrimSpace(target); len(target) > 0 {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, errNoTargets
+	}
+
+	if !retryhttpclient.IsRedirectPolicy(req.FollowRedirect) {
+		return nil, errors.New("unsupported redirect policy: " + req.FollowRedirect)
+	}
+
+	if _, err := portutil.ParsePorts(req.Ports); err != nil {
+		return nil, err
+	}
+
+	// 代理只接受请求中的列表，不能让 API 读取服务器上的文件
+	var proxies *proxypool.Pool
+	if len(strings.TrimSpace(req.Proxy)) > 0 {
+		pool, err := proxypool.NewList(req.Proxy, proxypool.ModeRoundRobin, time.Duration(pyxis.DefaultProxyRetry)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		proxies = pool
+	}
+
+	return &pyxis.Options{
+		Host:           targets,
+		Ports:          req.Ports,
+		Paths:          req.Paths,
+		Timeout:        req.Timeout,
+		Retries:        req.Retries,
+		RateLimit:      req.RateLimit,
+		Proxy:          req.Proxy,
+		ProxyPool:      proxies,
+		FollowRedirect: req.FollowRedirect,
+		Cdn:            req.Cdn,
+		TLSSan:         req.TLSSan,
+		TLSFingerprint: req.TLSFingerprint,
+		Headers:        req.Headers,
+		Cookie:         req.Cookie,
+		Method:         req.Method,
+		Body:           req.Body,
+		UserAgent:      req.UserAgent,
+
+		// 任务只保留输出字段，响应内容在指纹识别后丢弃
+		Stream:   true,
+		NoStdout: true,
+	}, nil
+}
+
+// JobStatus 是任务状态和进度的 JSON 表示
+type JobStatus struct {
+	ID       string      `json:"id"`
+	Status   string      `json:"status"`
+	Error    string      `json:"error,omitempty"`
+	Targets  int         `json:"targets"`
+	Results  int         `json:"results"`
+	Progress pyxis.Stats `json:"progress"`
+	Created  time.Time   `json:"created"`
+	Started  *time.Time  `json:"started,omitempty"`
+	Finished *time.Time  `json:"finished,omitempty"`
+}
+
+// Job 是一个扫描任务，结果按完成顺序追加，失败的结果带有 failure 原因
+type Job struct {
+	ID string
+
+	mu       sync.Mutex
+	status   string
+	err      string
+	targets  int
+	created  time.Time
+	started  time.Time
+	finished time.Time
+	results  []*pyxis.OutputResult
+	seen     map[string]struct{}
+	changed  chan struct{} // 有新结果或状态变化时关闭并替换，用于唤醒 SSE 订阅者
+
+	scanner *pyxis.Scanner
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func newJob(req *JobRequest) (*Job, error) {
+	options, err := req.options()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:      newJobID(),
+		status:  StatusQueued,
+		targets: len(options.Host),
+		created: time.Now(),
+		seen:    make(map[string]struct{}),
+		changed: make(chan struct{}),
+	}
+	options.OnResult = job.add
+
+	if job.scanner, err = pyxis.NewScanner(options); err != nil {
+		return nil, err
+	}
+	job.ctx, job.cancel = context.WithCancel(context.Background())
+
+	return job, nil
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// run 等待空闲的任务槽位后执行扫描，排队期间取消的任务不会开始
+func (job *Job) run(slots chan struct{}) {
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	case <-job.ctx.Done():
+		job.setStatus(StatusCanceled, "")
+		return
+	}
+
+	job.mu.Lock()
+	job.status = StatusRunning
+	job.started = time.Now()
+	job.notify()
+	job.mu.Unlock()
+
+	err := job.scanner.RunContext(job.ctx)
+	switch {
+	case job.ctx.Err() != nil:
+		job.setStatus(StatusCanceled, "")
+	case err != nil:
+		job.setStatus(StatusFailed, err.Error())
+	default:
+		job.setStatus(StatusFinished, "")
+	}
+}
+
+// Cancel 取消任务，已完成的结果仍然保留
+func (job *Job) Cancel() {
+	job.cancel()
+}
+
+func (job *Job) setStatus(status, err string) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.status = status
+	job.err = err
+	job.finished = time.Now()
+	job.notify()
+}
+
+// add 接收 Scanner 回调的结果，按 URL 去重，失败的结果按 host 去重
+func (job *Job) add(rst *result.HostResult) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if _, ok := job.seen[rst.Key()]; ok {
+		return
+	}
+	job.seen[rst.Key()] = struct{}{}
+	job.results = append(job.results, pyxis.NewOutputResult(rst))
+	job.notify()
+}
+
+// notify 唤醒等待中的订阅者，调用方需持有锁
+func (job *Job) notify() {
+	close(job.changed)
+	job.changed = make(chan struct{})
+}
+
+// finishedAt 返回任务结束的时间，未结束时返回零值
+func (job *Job) finishedAt() time.Time {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if !job.done() {
+		return time.Time{}
+	}
+	return job.finished
+}
+
+func (job *Job) done() bool {
+	switch job.status {
+	case StatusFinished, StatusCanceled, StatusFailed:
+		return true
+	}
+	return false
+}
+
+func (job *Job) Status() JobStatus {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	status := JobStatus{
+		ID:       job.ID,
+		Status:   job.status,
+		Error:    job.err,
+		Targets:  job.targets,
+		Results:  len(job.results),
+		Progress: job.scanner.Stats(),
+		Created:  job.created,
+	}
+	if !job.started.IsZero() {
+		started := job.started
+		status.Started = &started
+	}
+	if !job.finished.IsZero() {
+		finished := job.finished
+		status.Finished = &finished
+	}
+	return status
+}
+
+// Results 返回 [offset, offset+limit) 范围内的结果和结果总数
+func (job *Job) Results(offset, limit int) ([]*pyxis.OutputResult, int) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	total := len(job.results)
+	if offset >= total {
+		return []*pyxis.OutputResult{}, total
+	}
+	end := min(offset+limit, total)
+	return append([]*pyxis.OutputResult(nil), job.results[offset:end]...), total
+}
+
+// since 返回第 next 个之后的结果、下一次变化的通知通道以及任务是否已结束
+func (job *Job) since(next int) ([]*pyxis.OutputResult, <-chan struct{}, bool) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	var results []*pyxis.OutputResult
+	if next < len(job.results) {
+		results = append(results, job.results[next:]...)
+	}
+	return results, job.changed, job.done()
+}
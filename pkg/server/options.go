@@ -0,0 +1,74 @@
+package server
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zan8in/goflags"
+	"github.com/zan8in/gologger"
+	"github.com/zan8in/pyxis/pkg/pyxis"
+)
+
+const (
+	DefaultListen  = "127.0.0.1:8080"
+	DefaultMaxJobs = 2
+
+	DefaultJobTTL          = time.Hour
+	DefaultMaxFinishedJobs = 100
+)
+
+type Options struct {
+	Listen  string // Listen is the address the api server listens on
+	Token   string // Token is the bearer token required by every request, empty disables auth
+	MaxJobs int    // MaxJobs is the number of jobs running at the same time, the rest wait in queue
+
+	JobTTL          time.Duration // JobTTL is how long finished jobs are kept, 0 keeps them until deleted
+	MaxFinishedJobs int           // MaxFinishedJobs is the number of finished jobs kept, the oldest are removed first, 0 is unlimited
+
+	Silent bool // Silent is the flag to hide the banner
+}
+
+// ParseOptions 解析 pyxis serve 的参数，调用前需要从 os.Args 中移除 serve 子命令
+func ParseOptions() *Options {
+
+	options := &Options{}
+
+	flagSet := goflags.NewFlagSet()
+	flagSet.SetDescription(`Pyxis api server`)
+
+	flagSet.CreateGroup("server", "Server",
+		flagSet.StringVar(&options.Listen, "listen", DefaultListen, "address to listen on"),
+		flagSet.StringVar(&options.Token, "token", "", "bearer token required by every request (optional)"),
+		flagSet.IntVar(&options.MaxJobs, "max-jobs", DefaultMaxJobs, "number of scan jobs to run at the same time"),
+		flagSet.DurationVar(&options.JobTTL, "job-ttl", DefaultJobTTL, "how long finished jobs and their results are kept (0 keeps them until deleted)"),
+		flagSet.IntVar(&options.MaxFinishedJobs, "max-finished-jobs", DefaultMaxFinishedJobs, "number of finished jobs kept, the oldest are removed first (0 is unlimited)"),
+		flagSet.BoolVar(&options.Silent, "silent", false, "hide the banner"),
+	)
+
+	_ = flagSet.Parse()
+
+	if !options.Silent {
+		pyxis.ShowBanner()
+	}
+
+	if err := options.validateOptions(); err != nil {
+		gologger.Fatal().Msgf("Program exiting: %s\n", err)
+	}
+
+	return options
+}
+
+var errZeroValue = errors.New("cannot be zero")
+
+func (options *Options) validateOptions() error {
+	if options.MaxJobs <= 0 {
+		return errors.Wrap(errZeroValue, "max-jobs")
+	}
+	if options.JobTTL < 0 {
+		return errors.New("job-ttl cannot be negative")
+	}
+	if options.MaxFinishedJobs < 0 {
+		return errors.New("max-finished-jobs cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zan8in/gologger"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+
+	// SSE 心跳间隔，避免空闲连接被代理断开
+	keepAliveInterval = 15 * time.Second
+
+	maxRequestBody = 10 << 20 // 提交任务的请求体上限
+
+	evictInterval = time.Minute // 检查过期任务的间隔
+)
+
+// Server 通过 HTTP 接口提交和管理扫描任务，任务保存在内存中，
+// 结束的任务超过 -job-ttl 或数量超过 -max-finished-jobs 时被删除
+type Server struct {
+	options *Options
+
+	mu    sync.RWMutex
+	jobs  map[string]*Job
+	slots chan struct{}
+	stop  chan struct{}
+
+	httpServer *http.Server
+}
+
+func New(options *Options) *Server {
+	s := &Server{
+		options: options,
+		jobs:    make(map[string]*Job),
+		slots:   make(chan struct{}, options.MaxJobs),
+		stop:    make(chan struct{}),
+	}
+
+	s.httpServer = &http.Server{
+		Addr:              options.Listen,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go s.evictLoop()
+
+	return s
+}
+
+// Handler 返回 API 路由，可挂载到调用方自己的 HTTP 服务上
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /api/v1/jobs", s.handleSubmit)
+	mux.HandleFunc("GET /api/v1/jobs", s.handleList)
+	mux.HandleFunc("GET /api/v1/jobs/{id}", s.handleStatus)
+	mux.HandleFunc("GET /api/v1/jobs/{id}/results", s.handleResults)
+	mux.HandleFunc("GET /api/v1/jobs/{id}/events", s.handleEvents)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("DELETE /api/v1/jobs/{id}", s.handleDelete)
+
+	return s.auth(mux)
+}
+
+func (s *Server) ListenAndServe() error {
+	gologger.Info().Msgf("API server listening on http://%s", s.options.Listen)
+
+	if err := s.httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown 取消所有任务并关闭 HTTP 服务
+func (s *Server) Shutdown(ctx context.Context) error {
+	close(s.stop)
+
+	s.mu.RLock()
+	for _, job := range s.jobs {
+		job.Cancel()
+	}
+	s.mu.RUnlock()
+
+	return s.httpServer.Shutdown(ctx)
+}
+
+// auth 校验 Bearer token，EventSource 无法设置请求头，因此也接受 token 查询参数
+func (s *Server) auth(next http.Handler) http.Handler {
+	if len(s.options.Token) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if len(token) == 0 {
+			token = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.options.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	job, err := newJob(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	go job.run(s.slots)
+	s.evict()
+
+	writeJSON(w, http.StatusCreated, job.Status())
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	list := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		list = append(list, job.Status())
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.Before(list[j].Created)
+	})
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if job := s.job(w, r); job != nil {
+		writeJSON(w, http.StatusOK, job.Status())
+	}
+}
+
+// handleResults 分页返回结果，offset 从 0 开始，limit 默认 100，最大 1000
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	job := s.job(w, r)
+	if job == nil {
+		return
+	}
+
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "invalid offset")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultPageLimit)
+	if err != nil || limit <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	limit = min(limit, maxPageLimit)
+
+	results, total := job.Results(offset, limit)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+		"results": results,
+	})
+}
+
+// handleEvents 以 SSE 推送结果：先补发已有结果，再实时推送新结果，任务结束时发送 done 事件。
+// 事件 id 为结果序号，断线重连时通过 Last-Event-ID 从中断处继续
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	job := s.job(w, r)
+	if job == nil {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	next, err := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	if err != nil || next < 0 {
+		next = 0
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		results, changed, done := job.since(next)
+		for _, rst := range results {
+			next++
+			if err := writeEvent(w, next, "result", rst); err != nil {
+				return
+			}
+		}
+		if done {
+			writeEvent(w, next, "done", job.Status())
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if job := s.job(w, r); job != nil {
+		job.Cancel()
+		writeJSON(w, http.StatusAccepted, job.Status())
+	}
+}
+
+// handleDelete 取消任务并从内存中删除
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	job := s.job(w, r)
+	if job == nil {
+		return
+	}
+
+	job.Cancel()
+
+	s.mu.Lock()
+	delete(s.jobs, job.ID)
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) evictLoop() {
+	ticker := time.NewTicker(evictInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evict()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// evict 删除结束超过 -job-ttl 的任务，结束的任务超过 -max-finished-jobs 时删除最早结束的
+func (s *Server) evict() {
+	type finishedJob struct {
+		id       string
+		finished time.Time
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var finished []finishedJob
+	for id, job := range s.jobs {
+		at := job.finishedAt()
+		if at.IsZero() {
+			continue
+		}
+		if s.options.JobTTL > 0 && now.Sub(at) > s.options.JobTTL {
+			delete(s.jobs, id)
+			continue
+		}
+		finished = append(finished, finishedJob{id, at})
+	}
+
+	if s.options.MaxFinishedJobs <= 0 || len(finished) <= s.options.MaxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].finished.Before(finished[j].finished)
+	})
+	for _, job := range finished[:len(finished)-s.options.MaxFinishedJobs] {
+		delete(s.jobs, job.id)
+	}
+}
+
+// job 按路径中的 id 查找任务，不存在时写入 404
+func (s *Server) job(w http.ResponseWriter, r *http.Request) *Job {
+	s.mu.RLock()
+	job, ok := s.jobs[r.PathValue("id")]
+	s.mu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return nil
+	}
+	return job
+}
+
+func queryInt(r *http.Request, key string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if len(value) == 0 {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+func writeEvent(w http.ResponseWriter, id int, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, data)
+	return err
+}
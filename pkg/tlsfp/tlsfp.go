@@ -0,0 +1,156 @@
+package tlsfp
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Empty is the fingerprint of a server that failed every probe
+var Empty = strings.Repeat("0", 62)
+
+type probe struct {
+	minVersion uint16
+	maxVersion uint16
+	ciphers    []uint16
+	alpn       []string
+	curves     []tls.CurveID
+}
+
+// cipherSuites is the fixed table used to encode the negotiated cipher suite,
+// the order must never change otherwise fingerprints are no longer comparable
+var cipherSuites = []uint16{
+	tls.TLS_RSA_WITH_RC4_128_SHA,
+	tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_AES_128_GCM_SHA256,
+	tls.TLS_AES_256_GCM_SHA384,
+	tls.TLS_CHACHA20_POLY1305_SHA256,
+}
+
+// probes is the fixed set of ClientHellos, each one varies the offered
+// versions, cipher suites, ALPN and curves so servers with different stacks or
+// configurations answer differently. crypto/tls ignores the order of
+// CipherSuites, so probes only differ in what they offer, never in its order
+var probes = []probe{
+	{tls.VersionTLS12, tls.VersionTLS12, cipherSuites[:22], []string{"h2", "http/1.1"}, nil},
+	{tls.VersionTLS12, tls.VersionTLS12, cipherSuites[:22], []string{"h2", "http/1.1"}, []tls.CurveID{tls.CurveP384}},
+	{tls.VersionTLS12, tls.VersionTLS12, cipherSuites[:22], nil, nil},
+	{tls.VersionTLS10, tls.VersionTLS11, cipherSuites[:22], []string{"http/1.1"}, nil},
+	{tls.VersionTLS10, tls.VersionTLS10, cipherSuites[:22], nil, nil},
+	{tls.VersionTLS12, tls.VersionTLS12, []uint16{
+		tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+		tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	}, []string{"http/1.1"}, nil},
+	{tls.VersionTLS12, tls.VersionTLS12, []uint16{
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	}, []string{"h2"}, nil},
+	{tls.VersionTLS10, tls.VersionTLS13, cipherSuites[:22], []string{"h2", "http/1.1"}, nil},
+	{tls.VersionTLS13, tls.VersionTLS13, nil, []string{"http/1.1"}, nil},
+	{tls.VersionTLS13, tls.VersionTLS13, nil, nil, nil},
+}
+
+// Fingerprint performs the fixed set of handshakes against address (host:port)
+// and returns a 62 characters fingerprint: 3 characters per probe encoding the
+// negotiated cipher suite and version, followed by a truncated sha256 of the
+// negotiated ALPN values
+func Fingerprint(ctx context.Context, address, serverName string, timeout time.Duration) string {
+	var (
+		codes strings.Builder
+		alpn  []string
+	)
+
+	for _, p := range probes {
+		state, err := handshake(ctx, address, serverName, timeout, p)
+		if err != nil {
+			codes.WriteString("000")
+			alpn = append(alpn, "")
+			continue
+		}
+		codes.WriteString(fmt.Sprintf("%02x%s", cipherIndex(state.CipherSuite), versionCode(state.Version)))
+		alpn = append(alpn, state.NegotiatedProtocol)
+	}
+
+	if codes.String() == strings.Repeat("0", len(probes)*3) {
+		return Empty
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(alpn, ",")))
+	return codes.String() + hex.EncodeToString(sum[:])[:32]
+}
+
+func handshake(ctx context.Context, address, serverName string, timeout time.Duration, p probe) (tls.ConnectionState, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+
+	client := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		MinVersion:         p.minVersion,
+		MaxVersion:         p.maxVersion,
+		CipherSuites:       p.ciphers,
+		NextProtos:         p.alpn,
+		CurvePreferences:   p.curves,
+	})
+	if err := client.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	return client.ConnectionState(), nil
+}
+
+func cipherIndex(id uint16) int {
+	for i, c := range cipherSuites {
+		if c == id {
+			return i + 1
+		}
+	}
+	return 0xff
+}
+
+func versionCode(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "a"
+	case tls.VersionTLS11:
+		return "b"
+	case tls.VersionTLS12:
+		return "c"
+	case tls.VersionTLS13:
+		return "d"
+	}
+	return "0"
+}
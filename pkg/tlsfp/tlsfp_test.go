@@ -0,0 +1,118 @@
+package tlsfp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// serve 启动一个只完成握手的 TLS 服务，返回监听地址
+func serve(t *testing.T, config *tls.Config) string {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestFingerprint(t *testing.T) {
+	cert := testCertificate(t)
+
+	servers := map[string]*tls.Config{
+		"default": {
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{"h2", "http/1.1"},
+		},
+		"tls12 gcm": {
+			Certificates: []tls.Certificate{cert},
+			MaxVersion:   tls.VersionTLS12,
+			CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			NextProtos:   []string{"http/1.1"},
+		},
+		// 与 default 只有支持的曲线不同
+		"x25519 only": {
+			Certificates:     []tls.Certificate{cert},
+			NextProtos:       []string{"h2", "http/1.1"},
+			CurvePreferences: []tls.CurveID{tls.X25519},
+		},
+	}
+
+	fingerprints := make(map[string]string)
+	for name, config := range servers {
+		address := serve(t, config)
+
+		fp := Fingerprint(context.Background(), address, "localhost", 5*time.Second)
+		if len(fp) != len(Empty) || fp == Empty {
+			t.Fatalf("%s: unexpected fingerprint %q", name, fp)
+		}
+		if again := Fingerprint(context.Background(), address, "localhost", 5*time.Second); again != fp {
+			t.Errorf("%s: fingerprint is not stable: %s != %s", name, fp, again)
+		}
+
+		for other, otherFp := range fingerprints {
+			if otherFp == fp {
+				t.Errorf("%s and %s have the same fingerprint %s", name, other, fp)
+			}
+		}
+		fingerprints[name] = fp
+	}
+}
+
+func TestFingerprintUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ln.Close()
+
+	if fp := Fingerprint(context.Background(), address, "", time.Second); fp != Empty {
+		t.Errorf("Fingerprint of closed port = %s, want Empty", fp)
+	}
+}
@@ -42,6 +42,7 @@ const (
 	FILE_TXT = iota
 	FILE_JSON
 	FILE_CSV
+	FILE_JSONL
 	NOT_FOUND
 )
 
@@ -54,6 +55,8 @@ func FileExt(filename string) FileType {
 		return FILE_JSON
 	case ".csv":
 		return FILE_CSV
+	case ".jsonl":
+		return FILE_JSONL
 	default:
 		return NOT_FOUND
 	}
@@ -64,3 +67,16 @@ func BufferWriteAppend(file *os.File, content string) error {
 	buf.WriteString(content)
 	return buf.Flush()
 }
+
+// HasStdin determines if the user has piped input
+func HasStdin() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+
+	isPipedFromChrDev := (stat.Mode() & os.ModeCharDevice) == 0
+	isPipedFromFIFO := (stat.Mode() & os.ModeNamedPipe) != 0
+
+	return isPipedFromChrDev || isPipedFromFIFO
+}
@@ -0,0 +1,78 @@
+package hashutil
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func MD5(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func SHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Simhash 计算文本的 64 位 simhash，特征为相邻三个词组成的片段。
+// 含数字的词（时间戳、ID、token 等）通常每次请求都不同，不参与计算。
+// 相似的页面只有少数位不同，用 Distance 比较
+func Simhash(text string) uint64 {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if !strings.ContainsFunc(word, unicode.IsDigit) {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	h := fnv.New64a()
+	for i := 0; i < max(len(words)-2, 1); i++ {
+		h.Reset()
+		h.Write([]byte(strings.Join(words[i:min(i+3, len(words))], " ")))
+		sum := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if sum&(1<<b) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var simhash uint64
+	for b := 0; b < 64; b++ {
+		if weights[b] > 0 {
+			simhash |= 1 << b
+		}
+	}
+	return simhash
+}
+
+// Distance 返回两个 simhash 不同的位数
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FormatSimhash 将 simhash 格式化为 16 位十六进制
+func FormatSimhash(simhash uint64) string {
+	s := strconv.FormatUint(simhash, 16)
+	return strings.Repeat("0", 16-len(s)) + s
+}
+
+// ParseSimhash 解析 FormatSimhash 的结果
+func ParseSimhash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
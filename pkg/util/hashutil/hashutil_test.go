@@ -0,0 +1,83 @@
+package hashutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// page 模拟一个几百个词的页面，simhash 在这个长度上才稳定
+func page(topics ...string) string {
+	var b strings.Builder
+	b.WriteString("This domain is parked free of charge. The owner may be willing to sell it. ")
+	for _, topic := range topics {
+		fmt.Fprintf(&b, "Related searches for %s: compare %s offers, cheap %s deals and the best %s reviews of the year. ", topic, topic, topic, topic)
+	}
+	b.WriteString("Privacy policy. Terms of service. Copyright all rights reserved.")
+	return b.String()
+}
+
+var topics = []string{
+	"hosting", "email", "domains", "insurance", "loans", "travel", "flights", "hotels",
+	"cars", "phones", "laptops", "furniture", "gardening", "recipes", "fitness", "music",
+	"movies", "books", "courses", "jobs", "dating", "pets", "shoes", "watches",
+}
+
+func TestSimhash(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        string
+		maxDistance int
+		minDistance int
+	}{
+		{"identical", page(topics...), page(topics...), 0, 0},
+		{"numbers ignored", page(topics...) + " request 8f3a91 at 1700000000", page(topics...) + " request 77c2e0 at 1700000042", 0, 0},
+		{"one sentence added", page(topics...), page(topics...) + " Powered by openresty.", 3, 0},
+		{"one topic changed", page(topics...), page(append([]string{"gaming"}, topics[1:]...)...), 3, 0},
+		{"different page", page(topics...), page(topics[:4]...), 64, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Distance(Simhash(tt.a), Simhash(tt.b))
+			if d > tt.maxDistance || d < tt.minDistance {
+				t.Errorf("Distance = %d, want between %d and %d", d, tt.minDistance, tt.maxDistance)
+			}
+		})
+	}
+}
+
+func TestSimhashEmpty(t *testing.T) {
+	if h := Simhash("123 456 !!"); h != 0 {
+		t.Errorf("Simhash of text without words = %x, want 0", h)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0xff, 0x0f, 4},
+		{0, ^uint64(0), 64},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%x, %x) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSimhash(t *testing.T) {
+	for _, h := range []uint64{0, 1, 0xdeadbeef, ^uint64(0)} {
+		s := FormatSimhash(h)
+		if len(s) != 16 {
+			t.Errorf("FormatSimhash(%x) = %q, want 16 characters", h, s)
+		}
+		if got, err := ParseSimhash(s); err != nil || got != h {
+			t.Errorf("ParseSimhash(%q) = %x, %v, want %x", s, got, err, h)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"strings"
 
@@ -240,3 +241,66 @@ func GetDomainIP(target string) string {
 
 	return ipStr
 }
+
+// IsIPRange checks if the string is a dash separated ip range (e.g. 10.0.0.1-10.0.0.50)
+func IsIPRange(str string) bool {
+	start, end, ok := strings.Cut(str, "-")
+	if !ok {
+		return false
+	}
+	startIP, err := netip.ParseAddr(strings.TrimSpace(start))
+	if err != nil {
+		return false
+	}
+	endIP, err := netip.ParseAddr(strings.TrimSpace(end))
+	if err != nil {
+		return false
+	}
+	return startIP.Is4() == endIP.Is4()
+}
+
+// WalkCIDR calls fn for every address of the cidr (IPV4 & IPV6) in order.
+// Addresses are generated one by one so that huge networks never need to be
+// held in memory, the walk stops as soon as fn returns false.
+func WalkCIDR(cidr string, fn func(ip string) bool) error {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return err
+	}
+	prefix = prefix.Masked()
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		if !fn(addr.String()) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// WalkIPRange calls fn for every address between the two ends of a dash
+// separated ip range (both inclusive), the walk stops as soon as fn returns false.
+func WalkIPRange(ipRange string, fn func(ip string) bool) error {
+	start, end, ok := strings.Cut(ipRange, "-")
+	if !ok {
+		return fmt.Errorf("invalid ip range: %s", ipRange)
+	}
+	startIP, err := netip.ParseAddr(strings.TrimSpace(start))
+	if err != nil {
+		return err
+	}
+	endIP, err := netip.ParseAddr(strings.TrimSpace(end))
+	if err != nil {
+		return err
+	}
+	if startIP.Is4() != endIP.Is4() {
+		return fmt.Errorf("invalid ip range: %s, mixed ip versions", ipRange)
+	}
+	if startIP.Compare(endIP) > 0 {
+		return fmt.Errorf("invalid ip range: %s, start is greater than end", ipRange)
+	}
+	for addr := startIP; addr.IsValid() && addr.Compare(endIP) <= 0; addr = addr.Next() {
+		if !fn(addr.String()) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package iputil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsIPRange(t *testing.T) {
+	tests := []struct {
+		str  string
+		want bool
+	}{
+		{"10.0.0.1-10.0.0.50", true},
+		{"10.0.0.1 - 10.0.0.50", true},
+		{"2001:db8::1-2001:db8::ff", true},
+		{"10.0.0.1-2001:db8::1", false},
+		{"10.0.0.1", false},
+		{"10.0.0.0/24", false},
+		{"10.0.0.1-50", false},
+		{"example.com-example.org", false},
+	}
+	for _, tt := range tests {
+		if got := IsIPRange(tt.str); got != tt.want {
+			t.Errorf("IsIPRange(%q) = %v, want %v", tt.str, got, tt.want)
+		}
+	}
+}
+
+// walk 收集 fn 收到的地址，最多 limit 个
+func walk(t *testing.T, walkFn func(string, func(string) bool) error, str string, limit int) ([]string, error) {
+	t.Helper()
+
+	var ips []string
+	err := walkFn(str, func(ip string) bool {
+		ips = append(ips, ip)
+		return len(ips) < limit
+	})
+	return ips, err
+}
+
+func TestWalkCIDR(t *testing.T) {
+	tests := []struct {
+		cidr    string
+		limit   int
+		want    []string
+		wantErr bool
+	}{
+		{"192.168.1.0/30", 10, []string{"192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3"}, false},
+		{"192.168.1.5/30", 10, []string{"192.168.1.4", "192.168.1.5", "192.168.1.6", "192.168.1.7"}, false},
+		{"10.0.0.1/32", 10, []string{"10.0.0.1"}, false},
+		{"10.0.0.0/8", 3, []string{"10.0.0.0", "10.0.0.1", "10.0.0.2"}, false},
+		{"2001:db8::/126", 10, []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"}, false},
+		{"2001:db8::/32", 2, []string{"2001:db8::", "2001:db8::1"}, false},
+		{"255.255.255.254/31", 10, []string{"255.255.255.254", "255.255.255.255"}, false},
+		{"10.0.0.0/33", 10, nil, true},
+		{"example.com", 10, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := walk(t, WalkCIDR, tt.cidr, tt.limit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("WalkCIDR(%q) error = %v, wantErr %v", tt.cidr, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("WalkCIDR(%q) = %v, want %v", tt.cidr, got, tt.want)
+		}
+	}
+}
+
+func TestWalkIPRange(t *testing.T) {
+	tests := []struct {
+		ipRange string
+		limit   int
+		want    []string
+		wantErr bool
+	}{
+		{"10.0.0.254-10.0.1.1", 10, []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"}, false},
+		{"10.0.0.1-10.0.0.1", 10, []string{"10.0.0.1"}, false},
+		{"10.0.0.1-10.255.255.255", 2, []string{"10.0.0.1", "10.0.0.2"}, false},
+		{"2001:db8::fffe-2001:db8::1:1", 10, []string{"2001:db8::fffe", "2001:db8::ffff", "2001:db8::1:0", "2001:db8::1:1"}, false},
+		{"255.255.255.254-255.255.255.255", 10, []string{"255.255.255.254", "255.255.255.255"}, false},
+		{"10.0.0.5-10.0.0.1", 10, nil, true},
+		{"10.0.0.1-2001:db8::1", 10, nil, true},
+		{"10.0.0.1", 10, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := walk(t, WalkIPRange, tt.ipRange, tt.limit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("WalkIPRange(%q) error = %v, wantErr %v", tt.ipRange, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("WalkIPRange(%q) = %v, want %v", tt.ipRange, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package portutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Presets contains the named port lists that can be used in place of ports
+var Presets = map[string]string{
+	"top-web-10": "80,443,7001,8000,8080,8081,8443,8888,9000,9090",
+	"top-web-100": "80,81,82,83,84,85,86,87,88,89,90,443,444,591,593,800,801,808,880,888," +
+		"1080,1311,2000,2082,2083,2086,2087,2095,2096,2480,3000,3001,3128,3333,4000,4040," +
+		"4443,4567,4848,5000,5001,5104,5280,5601,5800,6080,6443,7000,7001,7002,7080,7443," +
+		"7474,7777,8000,8001,8008,8009,8010,8042,8060,8069,8080,8081,8082,8083,8088,8089," +
+		"8090,8091,8118,8123,8161,8181,8200,8243,8280,8333,8443,8500,8834,8880,8888,8983," +
+		"9000,9001,9043,9060,9080,9090,9091,9200,9443,9800,9981,10000,10443,12443,16080,18080",
+}
+
+// ParsePorts parses a comma separated list of ports, port ranges (8000-8100)
+// and named presets (top-web-100) into a list without duplicates
+func ParsePorts(str string) ([]int, error) {
+	var (
+		ports []int
+		seen  = make(map[int]struct{})
+	)
+
+	add := func(port int) {
+		if _, ok := seen[port]; !ok {
+			seen[port] = struct{}{}
+			ports = append(ports, port)
+		}
+	}
+
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		if preset, ok := Presets[strings.ToLower(item)]; ok {
+			presetPorts, err := ParsePorts(preset)
+			if err != nil {
+				return nil, err
+			}
+			for _, port := range presetPorts {
+				add(port)
+			}
+			continue
+		}
+
+		if start, end, ok := strings.Cut(item, "-"); ok {
+			startPort, err := parsePort(start)
+			if err != nil {
+				return nil, err
+			}
+			endPort, err := parsePort(end)
+			if err != nil {
+				return nil, err
+			}
+			if startPort > endPort {
+				return nil, fmt.Errorf("invalid port range: %s", item)
+			}
+			for port := startPort; port <= endPort; port++ {
+				add(port)
+			}
+			continue
+		}
+
+		port, err := parsePort(item)
+		if err != nil {
+			return nil, err
+		}
+		add(port)
+	}
+
+	return ports, nil
+}
+
+func parsePort(str string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(str))
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port: %s", str)
+	}
+	return port, nil
+}
@@ -0,0 +1,28 @@
+package portutil
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 预设的端口数与名称一致，且没有重复端口
+func TestPresets(t *testing.T) {
+	for name, preset := range Presets {
+		want, err := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+		if err != nil {
+			t.Fatalf("preset %s does not end with its size", name)
+		}
+
+		ports, err := ParsePorts(name)
+		if err != nil {
+			t.Fatalf("ParsePorts(%s): %v", name, err)
+		}
+		if len(ports) != want {
+			t.Errorf("%s has %d ports, want %d", name, len(ports), want)
+		}
+		if n := len(strings.Split(preset, ",")); n != len(ports) {
+			t.Errorf("%s lists %d ports but only %d are unique", name, n, len(ports))
+		}
+	}
+}